@@ -13,11 +13,25 @@ import (
 	"proyecto1/User"
 	"proyecto1/Utilities"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
 var re = regexp.MustCompile(`-(\w+)=("[^"]+"|\S+)`)
 
+// Función para verificar si el input contiene un parámetro sin valor (por ejemplo -compact)
+// Se compara cada palabra completa, sin contar los valores de los parámetros -nombre=valor
+// (que pueden tener espacios entre comillas)
+func hasFlag(input string, name string) bool {
+	for _, token := range strings.Fields(re.ReplaceAllString(input, " ")) {
+		if strings.EqualFold(token, "-"+name) {
+			return true
+		}
+	}
+	return false
+}
+
 // Helper para obtener el comando y sus parámetros de un input
 func getCommandAndParams(input string) (string, string) {
 	parts := strings.Fields(input)
@@ -54,20 +68,44 @@ func AnalyzeCommand(command string, params string) {
 
 	if strings.Contains(command, "mkdisk") {
 		fn_mkdisk(params)
+	} else if strings.Contains(command, "rmdisk") {
+		fn_rmdisk(params)
+	} else if strings.Contains(command, "cpart") {
+		fn_cpart(params)
+	} else if strings.Contains(command, "dumppart") {
+		fn_dumppart(params)
+	} else if strings.Contains(command, "loadpart") {
+		fn_loadpart(params)
 	} else if strings.Contains(command, "fdisk") {
 		fn_fdisk(params)
+	} else if strings.Contains(command, "unmount") { // Antes que mount, porque "unmount" contiene "mount"
+		fn_unmount(params)
 	} else if strings.Contains(command, "mount") {
 		fn_mount(params)
-	} else if strings.Contains(command, "unmount") {
-		fn_unmount(params)
+	} else if strings.Contains(command, "snapshot") {
+		fn_snapshot(params)
+	} else if strings.Contains(command, "rollback") {
+		fn_rollback(params)
+	} else if strings.Contains(command, "migrate") {
+		fn_migrate(params)
+	} else if strings.Contains(command, "recover") {
+		fn_recover(params)
 	} else if strings.Contains(command, "mkfs") {
 		fn_mkfs(params)
+	} else if strings.Contains(command, "resizefs") {
+		fn_resizefs(params)
 	} else if strings.Contains(command, "login") {
 		fn_login(params)
 	} else if strings.Contains(command, "rep") {
 		fn_rep(params)
 	} else if strings.Contains(command, "mkusr") {
 		fn_mkusr(params)
+	} else if strings.Contains(command, "mkdir") {
+		fn_mkdir(params)
+	} else if strings.Contains(command, "mkfile") {
+		fn_mkfile(params)
+	} else if strings.Contains(command, "cat") {
+		fn_cat(params)
 	} else if strings.Contains(command, "readmbr") {
 		fn_readmbr(params)
 	} else {
@@ -83,6 +121,8 @@ func fn_mkdisk(params string) {
 	fit := fs.String("fit", "ff", "Ajuste")
 	unit := fs.String("unit", "m", "Unidad")
 	path := fs.String("path", "", "Ruta")
+	table := fs.String("table", "mbr", "Tabla de particiones")
+	alloc := fs.String("alloc", "zero", "Asignación del disco")
 
 	// Encontrar las flags en el input
 	matches := re.FindAllStringSubmatch(params, -1)
@@ -94,7 +134,7 @@ func fn_mkdisk(params string) {
 		flagValue = strings.Trim(flagValue, "\"")
 
 		switch flagName {
-		case "size", "fit", "unit", "path":
+		case "size", "fit", "unit", "path", "table", "alloc":
 			fs.Set(flagName, flagValue)
 		default:
 			fmt.Println("Error: Flag no encontrada")
@@ -122,8 +162,254 @@ func fn_mkdisk(params string) {
 		return
 	}
 
+	if *table != "mbr" && *table != "gpt" {
+		fmt.Println("Error: La tabla de particiones debe ser 'mbr' o 'gpt'")
+		return
+	}
+
+	if *alloc != "sparse" && *alloc != "prealloc" && *alloc != "zero" {
+		fmt.Println("Error: La asignación debe ser 'sparse', 'prealloc' o 'zero'")
+		return
+	}
+
 	// Llamar a la función que ejecuta el mkdisk
-	DiskManagement.Mkdisk(*size, *fit, *unit, *path)
+	DiskManagement.Mkdisk(*size, *fit, *unit, *path, *table, *alloc)
+}
+
+// Función para eliminar un disco (rmdisk)
+func fn_rmdisk(params string) {
+	// Definir flag
+	fs := flag.NewFlagSet("rmdisk", flag.ExitOnError)
+	path := fs.String("path", "", "Ruta")
+
+	// Encontrar las flags en el input
+	matches := re.FindAllStringSubmatch(params, -1)
+
+	// Procesar el input
+	for _, match := range matches {
+		flagName := match[1]
+		flagValue := strings.ToLower(match[2])
+		flagValue = strings.Trim(flagValue, "\"")
+
+		switch flagName {
+		case "path":
+			fs.Set(flagName, flagValue)
+		default:
+			fmt.Println("Error: Flag no encontrada")
+		}
+	}
+
+	if *path == "" {
+		fmt.Println("Error: La ruta es requerida")
+		return
+	}
+
+	// Llamar a la función que elimina el disco
+	DiskManagement.Rmdisk(*path)
+}
+
+// Función para copiar una partición de un disco a otro (fn_cpart)
+func fn_cpart(params string) {
+	// Definir flags
+	fs := flag.NewFlagSet("cpart", flag.ExitOnError)
+	src := fs.String("src", "", "Disco origen")
+	srcName := fs.String("srcname", "", "Partición origen")
+	dest := fs.String("dest", "", "Disco destino")
+	name := fs.String("name", "", "Nombre de la copia")
+	type_ := fs.String("type", "p", "Tipo de la copia")
+
+	// Encontrar las flags en el input
+	matches := re.FindAllStringSubmatch(params, -1)
+
+	// Procesar el input
+	for _, match := range matches {
+		flagName := match[1]
+		flagValue := strings.ToLower(match[2])
+		flagValue = strings.Trim(flagValue, "\"")
+		fs.Set(flagName, flagValue)
+	}
+
+	if *src == "" || *srcName == "" || *dest == "" || *name == "" {
+		fmt.Println("Error: Para copiar una partición, se requiere 'src', 'srcname', 'dest' y 'name'.")
+		return
+	}
+
+	// Llamar a la función que copia la partición
+	DiskManagement.CopyPartition(*src, *srcName, *dest, *name, *type_)
+}
+
+// Función para exportar la imagen de una partición a un archivo (fn_dumppart)
+func fn_dumppart(params string) {
+	// Definir flags
+	fs := flag.NewFlagSet("dumppart", flag.ExitOnError)
+	id := fs.String("id", "", "ID de la partición montada")
+	path := fs.String("path", "", "Ruta del disco")
+	name := fs.String("name", "", "Nombre de la partición")
+	out := fs.String("out", "", "Archivo de salida")
+
+	// Encontrar las flags en el input
+	matches := re.FindAllStringSubmatch(params, -1)
+
+	// Procesar el input
+	for _, match := range matches {
+		flagName := match[1]
+		flagValue := strings.Trim(match[2], "\"")
+		// La ruta del archivo del host conserva sus mayúsculas
+		if flagName != "out" {
+			flagValue = strings.ToLower(flagValue)
+		}
+		fs.Set(flagName, flagValue)
+	}
+
+	if *out == "" || (*id == "" && (*path == "" || *name == "")) {
+		fmt.Println("Error: Para exportar una partición, se requiere 'out' y el 'id' o la 'path' y el 'name'.")
+		return
+	}
+
+	// Llamar a la función que exporta la partición
+	DiskManagement.DumpPartition(*path, *name, *id, *out)
+}
+
+// Función para importar una imagen en una partición existente (fn_loadpart)
+func fn_loadpart(params string) {
+	// Definir flags
+	fs := flag.NewFlagSet("loadpart", flag.ExitOnError)
+	path := fs.String("path", "", "Ruta del disco")
+	name := fs.String("name", "", "Nombre de la partición")
+	in := fs.String("in", "", "Archivo de la imagen")
+
+	// Encontrar las flags en el input
+	matches := re.FindAllStringSubmatch(params, -1)
+
+	// Procesar el input
+	for _, match := range matches {
+		flagName := match[1]
+		flagValue := strings.Trim(match[2], "\"")
+		// La ruta del archivo del host conserva sus mayúsculas
+		if flagName != "in" {
+			flagValue = strings.ToLower(flagValue)
+		}
+		fs.Set(flagName, flagValue)
+	}
+
+	if *path == "" || *name == "" || *in == "" {
+		fmt.Println("Error: Para importar una imagen, se requiere 'path', 'name' e 'in'.")
+		return
+	}
+
+	// Llamar a la función que importa la imagen
+	DiskManagement.LoadPartition(*path, *name, *in)
+}
+
+// Función para crear o listar snapshots de un disco (fn_snapshot)
+func fn_snapshot(params string) {
+	// Definir flags
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	path := fs.String("path", "", "Ruta")
+	name := fs.String("name", "", "Nombre")
+
+	// Encontrar las flags en el input
+	matches := re.FindAllStringSubmatch(params, -1)
+
+	// Procesar el input
+	for _, match := range matches {
+		flagName := match[1]
+		flagValue := strings.ToLower(match[2])
+		flagValue = strings.Trim(flagValue, "\"")
+		fs.Set(flagName, flagValue)
+	}
+
+	// Opción -list: mostrar los snapshots (de un disco si se indica path)
+	if hasFlag(params, "list") {
+		DiskManagement.PrintSnapshots(*path)
+		return
+	}
+
+	if *path == "" || *name == "" {
+		fmt.Println("Error: Para crear un snapshot, se requiere 'path' y 'name'.")
+		return
+	}
+
+	// Llamar a la función que crea el snapshot
+	DiskManagement.CreateSnapshot(*path, *name)
+}
+
+// Función para regresar un disco a un snapshot (fn_rollback)
+func fn_rollback(params string) {
+	// Definir flags
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	name := fs.String("name", "", "Nombre")
+
+	// Encontrar las flags en el input
+	matches := re.FindAllStringSubmatch(params, -1)
+
+	// Procesar el input
+	for _, match := range matches {
+		flagName := match[1]
+		flagValue := strings.ToLower(match[2])
+		flagValue = strings.Trim(flagValue, "\"")
+		fs.Set(flagName, flagValue)
+	}
+
+	if *name == "" {
+		fmt.Println("Error: El nombre del snapshot es requerido")
+		return
+	}
+
+	// Llamar a la función que restaura el disco (-force permite hacerlo con particiones montadas)
+	DiskManagement.Rollback(*name, hasFlag(params, "force"))
+}
+
+// Función para migrar un disco del formato de 32 bits al formato actual (fn_migrate)
+func fn_migrate(params string) {
+	// Definir flag
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	path := fs.String("path", "", "Ruta")
+
+	// Encontrar las flags en el input
+	matches := re.FindAllStringSubmatch(params, -1)
+
+	// Procesar el input
+	for _, match := range matches {
+		flagName := match[1]
+		flagValue := strings.ToLower(match[2])
+		flagValue = strings.Trim(flagValue, "\"")
+		fs.Set(flagName, flagValue)
+	}
+
+	if *path == "" {
+		fmt.Println("Error: La ruta es requerida")
+		return
+	}
+
+	// Llamar a la función que migra el disco
+	DiskManagement.MigrateDisk(*path)
+}
+
+// Función para recuperar particiones borradas (fn_recover)
+func fn_recover(input string) {
+	// Definir flag
+	fs := flag.NewFlagSet("recover", flag.ExitOnError)
+	path := fs.String("path", "", "Ruta")
+
+	// Encontrar las flags en el input
+	matches := re.FindAllStringSubmatch(input, -1)
+
+	// Procesar el input
+	for _, match := range matches {
+		flagName := match[1]
+		flagValue := strings.ToLower(match[2])
+		flagValue = strings.Trim(flagValue, "\"")
+		fs.Set(flagName, flagValue)
+	}
+
+	if *path == "" {
+		fmt.Println("Error: La ruta es requerida")
+		return
+	}
+
+	// Sin -confirm solo se muestran las particiones encontradas
+	DiskManagement.RecoverPartitions(*path, hasFlag(input, "confirm"))
 }
 
 // Funcion FDISK
@@ -137,6 +423,7 @@ func fn_fdisk(input string) {
 	type_ := fs.String("type", "p", "Tipo")
 	fit := fs.String("fit", "", "Ajuste")
 	delete_ := fs.String("delete", "", "Eliminar partición (Fast/Full)")
+	add := fs.Int("add", 0, "Espacio a agregar (positivo) o quitar (negativo)")
 
 	// Encontrar los flags en el input
 	matches := re.FindAllStringSubmatch(input, -1)
@@ -149,6 +436,26 @@ func fn_fdisk(input string) {
 		fs.Set(flagName, flagValue)
 	}
 
+	// Opción -compact: juntar todo el espacio libre al final del disco
+	if hasFlag(input, "compact") {
+		if *path == "" {
+			fmt.Println("Error: Para compactar un disco, se requiere 'path'.")
+			return
+		}
+		DiskManagement.CompactDisk(*path)
+		return
+	}
+
+	// Opción -check (y -repair): revisar la consistencia de la tabla de particiones
+	if hasFlag(input, "check") || hasFlag(input, "repair") {
+		if *path == "" {
+			fmt.Println("Error: Para revisar un disco, se requiere 'path'.")
+			return
+		}
+		DiskManagement.CheckDisk(*path, hasFlag(input, "repair"))
+		return
+	}
+
 	// Validaciones para la opción -delete
 	if *delete_ != "" {
 		if *path == "" || *name == "" {
@@ -160,6 +467,21 @@ func fn_fdisk(input string) {
 		return
 	}
 
+	// Validaciones para la opción -add (agregar o quitar espacio)
+	if *add != 0 {
+		if *path == "" || *name == "" {
+			fmt.Println("Error: Para modificar una partición, se requiere 'path' y 'name'.")
+			return
+		}
+		if *unit != "b" && *unit != "k" && *unit != "m" {
+			fmt.Println("Error: La unidad debe ser 'b', 'k' o 'm'")
+			return
+		}
+		// Llamar a la función que modifica el tamaño de la partición
+		DiskManagement.ModifyPartition(*path, *name, *add, *unit)
+		return
+	}
+
 	// Validaciones para la creación de particiones
 	if *size <= 0 {
 		fmt.Println("Error: El tamaño debe ser mayor a 0")
@@ -171,13 +493,8 @@ func fn_fdisk(input string) {
 		return
 	}
 
-	// Usar ajuste por defecto si no se proporciona
-	if *fit == "" {
-		*fit = "w"
-	}
-
-	// Validar fit
-	if *fit != "b" && *fit != "f" && *fit != "w" {
+	// Validar fit (si no se proporciona se usa el ajuste del disco)
+	if *fit != "" && *fit != "b" && *fit != "f" && *fit != "w" {
 		fmt.Println("Error: El ajuste debe ser 'b', 'f', o 'w'")
 		return
 	}
@@ -249,9 +566,7 @@ func fn_mount(params string) {
 		return
 	}
 
-	// Convertir el nombre a minúsculas antes de pasarlo al Mount
-	lowercaseName := strings.ToLower(*name)
-	DiskManagement.Mount(*path, lowercaseName)
+	DiskManagement.Mount(*path, *name)
 }
 
 // Función para desmontar particiones (fn_unmount)
@@ -320,6 +635,32 @@ func fn_mkfs(input string) {
 	FileSystem.Mkfs(*id, *type_, *fs_)
 }
 
+// Función para hacer crecer el sistema de archivos de una partición (fn_resizefs)
+func fn_resizefs(input string) {
+	// Definir flags
+	fs := flag.NewFlagSet("resizefs", flag.ExitOnError)
+	id := fs.String("id", "", "Id")
+
+	// Parsear los parámetros de entrada
+	matches := re.FindAllStringSubmatch(input, -1)
+
+	// Procesar los parámetros
+	for _, match := range matches {
+		flagName := match[1]
+		flagValue := strings.Trim(match[2], "\"")
+		fs.Set(flagName, flagValue)
+	}
+
+	// Validaciones
+	if *id == "" {
+		fmt.Println("Error: id es un parámetro obligatorio.")
+		return
+	}
+
+	// Llamar a la función que redimensiona el sistema de archivos
+	FileSystem.Resizefs(*id)
+}
+
 // Función para iniciar sesión (fn_login)
 func fn_login(input string) {
 	// Definir flags
@@ -405,6 +746,93 @@ func fn_mkusr(input string) {
 	fmt.Println("Usuario creado con éxito:", *user)
 }
 
+// Función para crear carpetas (fn_mkdir)
+func fn_mkdir(input string) {
+	// Definir flags
+	fs := flag.NewFlagSet("mkdir", flag.ExitOnError)
+	path := fs.String("path", "", "Ruta de la carpeta")
+
+	// Parsear los parámetros de entrada (la ruta conserva mayúsculas y minúsculas)
+	matches := re.FindAllStringSubmatch(input, -1)
+	for _, match := range matches {
+		flagName := match[1]
+		flagValue := strings.Trim(match[2], "\"")
+		fs.Set(flagName, flagValue)
+	}
+
+	if *path == "" {
+		fmt.Println("Error: La ruta es requerida")
+		return
+	}
+
+	// Con -p se crean también las carpetas padre que no existan
+	FileSystem.Mkdir(*path, hasFlag(input, "p"))
+}
+
+// Función para crear archivos (fn_mkfile)
+func fn_mkfile(input string) {
+	// Definir flags
+	fs := flag.NewFlagSet("mkfile", flag.ExitOnError)
+	path := fs.String("path", "", "Ruta del archivo")
+	size := fs.Int("size", 0, "Tamaño en bytes")
+	cont := fs.String("cont", "", "Archivo de la computadora con el contenido")
+
+	// Parsear los parámetros de entrada (las rutas conservan mayúsculas y minúsculas)
+	matches := re.FindAllStringSubmatch(input, -1)
+	for _, match := range matches {
+		flagName := match[1]
+		flagValue := strings.Trim(match[2], "\"")
+		if err := fs.Set(flagName, flagValue); err != nil {
+			fmt.Printf("Error: Valor inválido para -%s: %s\n", flagName, flagValue)
+			return
+		}
+	}
+
+	if *path == "" {
+		fmt.Println("Error: La ruta es requerida")
+		return
+	}
+
+	// Con -r se crean también las carpetas padre que no existan
+	FileSystem.Mkfile(*path, *size, hasFlag(input, "r"), *cont)
+}
+
+// Función para mostrar el contenido de archivos (fn_cat)
+func fn_cat(input string) {
+	// Los parámetros son -file1, -file2, ... -fileN y se leen en el orden de su número
+	files := map[int]string{}
+	matches := re.FindAllStringSubmatch(input, -1)
+	for _, match := range matches {
+		flagName := strings.ToLower(match[1])
+		flagValue := strings.Trim(match[2], "\"")
+
+		number, err := strconv.Atoi(strings.TrimPrefix(flagName, "file"))
+		if !strings.HasPrefix(flagName, "file") || err != nil || number < 1 {
+			fmt.Println("Error: Parámetro inválido:", match[1])
+			return
+		}
+		files[number] = flagValue
+	}
+
+	if len(files) == 0 {
+		fmt.Println("Error: Se necesita al menos el parámetro -file1")
+		return
+	}
+
+	numbers := make([]int, 0, len(files))
+	for number := range files {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	paths := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		paths = append(paths, files[number])
+	}
+
+	FileSystem.Cat(paths)
+}
+
 // Función para generar reportes (fn_rep)
 func fn_rep(input string) {
 	// Definir flags
@@ -429,17 +857,8 @@ func fn_rep(input string) {
 	}
 
 	// Verificar si el disco está montado
-	mounted := false
-	var diskPath string
-	for _, partitions := range DiskManagement.GetMountedPartitions() {
-		for _, partition := range partitions {
-			if partition.ID == *id {
-				mounted = true
-				diskPath = partition.Path
-				break
-			}
-		}
-	}
+	mountedPartition, mounted := DiskManagement.GetMountedPartition(*id)
+	diskPath := mountedPartition.Path
 
 	if !mounted {
 		fmt.Println("Error: La partición con ID", *id, "no está montada.")
@@ -488,6 +907,21 @@ func GenerateMBRReport(diskPath, reportPath string) {
 	}
 	defer file.Close()
 
+	// Los discos GPT tienen su propio reporte de la tabla de particiones
+	table, err := DiskManagement.ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if protective, header, entries, isGPT := DiskManagement.GPTDetails(table); isGPT {
+		if err := Utilities.GenerateGPTReport(protective, header, entries, reportPath, file); err != nil {
+			fmt.Println("Error al generar el reporte GPT:", err)
+		} else {
+			renderDotToImage(reportPath)
+		}
+		return
+	}
+
 	// Leer el MBR desde el archivo
 	var TempMBR Structs.MRB
 	if err := Utilities.ReadObject(file, &TempMBR, 0); err != nil {
@@ -499,14 +933,12 @@ func GenerateMBRReport(diskPath, reportPath string) {
 	var ebrs []Structs.EBR
 	for i := 0; i < 4; i++ {
 		if string(TempMBR.Partitions[i].Type[:]) == "e" { // Partición extendida
-			ebrPosition := TempMBR.Partitions[i].Start
-			for ebrPosition != -1 {
-				var tempEBR Structs.EBR
-				if err := Utilities.ReadObject(file, &tempEBR, int64(ebrPosition)); err != nil {
-					break
-				}
-				ebrs = append(ebrs, tempEBR)
-				ebrPosition = tempEBR.PartNext
+			chain, err := DiskManagement.ReadEBRChain(file, TempMBR.Partitions[i])
+			if err != nil {
+				fmt.Println("Error al leer EBR:", err)
+			}
+			for _, logical := range chain {
+				ebrs = append(ebrs, logical.EBR)
 			}
 		}
 	}
@@ -532,6 +964,21 @@ func GenerateDiskReport(diskPath, reportPath string) {
 	}
 	defer file.Close()
 
+	// Los discos GPT no tienen particiones extendidas ni EBRs
+	table, err := DiskManagement.ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if _, header, entries, isGPT := DiskManagement.GPTDetails(table); isGPT {
+		if err := Utilities.GenerateGPTDiskReport(header, entries, reportPath, file); err != nil {
+			fmt.Println("Error al generar el reporte DISK:", err)
+		} else {
+			renderDotToImage(reportPath)
+		}
+		return
+	}
+
 	// Leer el MBR desde el archivo
 	var TempMBR Structs.MRB
 	if err := Utilities.ReadObject(file, &TempMBR, 0); err != nil {
@@ -543,14 +990,12 @@ func GenerateDiskReport(diskPath, reportPath string) {
 	var ebrs []Structs.EBR
 	for i := 0; i < 4; i++ {
 		if string(TempMBR.Partitions[i].Type[:]) == "e" { // Partición extendida
-			ebrPosition := TempMBR.Partitions[i].Start
-			for ebrPosition != -1 {
-				var tempEBR Structs.EBR
-				if err := Utilities.ReadObject(file, &tempEBR, int64(ebrPosition)); err != nil {
-					break
-				}
-				ebrs = append(ebrs, tempEBR)
-				ebrPosition = tempEBR.PartNext
+			chain, err := DiskManagement.ReadEBRChain(file, TempMBR.Partitions[i])
+			if err != nil {
+				fmt.Println("Error al leer EBR:", err)
+			}
+			for _, logical := range chain {
+				ebrs = append(ebrs, logical.EBR)
 			}
 		}
 	}
@@ -0,0 +1,134 @@
+package Analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"proyecto1/DiskManagement"
+	"proyecto1/Utilities"
+)
+
+var testDir string
+
+func TestMain(m *testing.M) {
+	// fdisk pasa los valores a minúsculas, así que los discos de prueba van en un directorio con nombre en minúsculas
+	dir, err := os.MkdirTemp("", "analyzer")
+	if err != nil {
+		panic(err)
+	}
+	testDir = dir
+	DiskManagement.SetMountRegistryPath(filepath.Join(dir, "mount_registry.json"))
+	DiskManagement.SetSnapshotsDir(filepath.Join(dir, "snapshots"))
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestHasFlag(t *testing.T) {
+	for _, c := range []struct {
+		input    string
+		name     string
+		expected bool
+	}{
+		{"-path=/a.mia -check -repair", "repair", true},
+		{"-path=/a.mia -check -repair", "check", true},
+		{"-path=/a.mia -repair -check", "check", true},
+		{"-path=/a.mia -COMPACT", "compact", true},
+		{"-path=/a.mia -check", "repair", false},
+		// Los valores de los parámetros no cuentan como flags
+		{"-path=/tmp/-repair.mia -check", "repair", false},
+		{"-path=\"/mis discos/-repair\" -check", "repair", false},
+		{"-name=-compact -path=/a.mia", "compact", false},
+		// Solo se acepta la palabra completa
+		{"-path=/a.mia -checkall", "check", false},
+		{"-path=/a.mia -check=1", "check", false},
+	} {
+		if got := hasFlag(c.input, c.name); got != c.expected {
+			t.Errorf("hasFlag(%q, %q) = %v, se esperaba %v", c.input, c.name, got, c.expected)
+		}
+	}
+}
+
+// Función para leer el estado de montaje de la partición a
+func partitionStatus(t *testing.T, path string) byte {
+	t.Helper()
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	table, err := DiskManagement.ReadPartitionTable(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range table.Entries() {
+		if entry.Name == "a" {
+			return entry.Status
+		}
+	}
+	t.Fatal("no se encontró la partición a")
+	return 0
+}
+
+func TestFdiskCheckRepairInAnyOrder(t *testing.T) {
+	for i, params := range []string{
+		"-path=%s -check -repair",
+		"-repair -check -path=%s",
+		"-path=%s -repair",
+	} {
+		path := filepath.Join(testDir, fmt.Sprintf("repair%d.mia", i))
+		if err := DiskManagement.CreateStaleMountDisk(path, "991a"); err != nil {
+			t.Fatalf("no se pudo crear el disco: %v", err)
+		}
+		fn_fdisk(fmt.Sprintf(params, path))
+		if status := partitionStatus(t, path); status == '1' {
+			t.Errorf("%q no reparó el disco", params)
+		}
+	}
+
+	// Solo -check reporta sin modificar el disco
+	path := filepath.Join(testDir, "check.mia")
+	if err := DiskManagement.CreateStaleMountDisk(path, "991a"); err != nil {
+		t.Fatalf("no se pudo crear el disco: %v", err)
+	}
+	fn_fdisk("-check -path=" + path)
+	if status := partitionStatus(t, path); status != '1' {
+		t.Error("-check sin -repair modificó el disco")
+	}
+}
+
+func TestDumppartKeepsOutputPathCase(t *testing.T) {
+	DiskManagement.CleanMountedPartitions()
+	path := filepath.Join(testDir, "dump.mia")
+	DiskManagement.Mkdisk(1024, "ff", "k", path, "mbr", "sparse")
+	if err := DiskManagement.Fdisk(100, path, "a", "k", "p", ""); err != nil {
+		t.Fatalf("fdisk: %v", err)
+	}
+
+	image := filepath.Join(t.TempDir(), "Imagenes", "Particion_A.bin")
+	fn_dumppart("-path=" + path + " -name=A -out=" + image)
+	if _, err := os.Stat(image); err != nil {
+		t.Fatalf("dumppart no escribió la imagen en %s: %v", image, err)
+	}
+
+	// loadpart lee la imagen desde la misma ruta, con sus mayúsculas
+	if err := os.WriteFile(image, []byte("marca"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fn_loadpart("-path=" + path + " -name=a -in=" + image)
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	location, err := DiskManagement.FindPartitionByName(file, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 5)
+	if _, err := file.ReadAt(data, location.Start); err != nil || string(data) != "marca" {
+		t.Fatalf("loadpart no copió la imagen: %q", data)
+	}
+}
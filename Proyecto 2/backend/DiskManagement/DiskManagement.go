@@ -3,10 +3,16 @@ package DiskManagement
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"proyecto1/Structs"
 	"proyecto1/Utilities"
+	"sort"
 	"strings"
 	"time"
 )
@@ -21,27 +27,25 @@ func ReadMBR(path string) {
 	}
 	defer file.Close()
 
-	// Crear una variable para almacenar el MBR
-	var mbr Structs.MRB
-
-	// Leer el MBR desde el archivo
-	err = Utilities.ReadObject(file, &mbr, 0) // Leer desde la posición 0
+	// Leer la tabla de particiones (MBR o GPT) desde el archivo
+	table, err := ReadPartitionTable(file)
 	if err != nil {
 		fmt.Println("Error al leer el MBR:", err)
 		return
 	}
 
-	// Imprimir el MBR
-	Structs.PrintMBR(mbr)
+	// Imprimir la tabla de particiones
+	table.Print()
 }
 
 // Estructura para representar una partición en JSON
 type PartitionInfo struct {
 	Name   string `json:"name"`
 	Type   string `json:"type"`
-	Start  int32  `json:"start"`
-	Size   int32  `json:"size"`
+	Start  int64  `json:"start"`
+	Size   int64  `json:"size"`
 	Status string `json:"status"`
+	ID     string `json:"id,omitempty"` // ID de montaje si la partición está montada
 }
 
 // Función para leer el MBR desde un archivo binario y devolver las particiones
@@ -53,42 +57,172 @@ func ListPartitions(path string) ([]PartitionInfo, error) {
 	}
 	defer file.Close()
 
-	// Crear una variable para almacenar el MBR
-	var mbr Structs.MRB
-
-	// Leer el MBR desde el archivo
-	err = Utilities.ReadObject(file, &mbr, 0) // Leer desde la posición 0
+	// Leer la tabla de particiones (MBR o GPT) desde el archivo
+	table, err := ReadPartitionTable(file)
 	if err != nil {
 		return nil, fmt.Errorf("Error al leer el MBR: %v", err)
 	}
 
-	// Crear una lista de particiones basada en el MBR
+	// Crear una lista de particiones basada en la tabla (solo las entradas con tamaño)
 	var partitions []PartitionInfo
-	for _, partition := range mbr.Partitions {
-		if partition.Size > 0 { // Solo agregar si la partición tiene un tamaño
-			// Limpiar el nombre para eliminar caracteres nulos
-			partitionName := strings.TrimRight(string(partition.Name[:]), "\x00")
-
-			partitions = append(partitions, PartitionInfo{
-				Name:   partitionName,
-				Type:   strings.TrimRight(string(partition.Type[:]), "\x00"),
-				Start:  partition.Start,
-				Size:   partition.Size,
-				Status: strings.TrimRight(string(partition.Status[:]), "\x00"),
-			})
-		}
+	for _, partition := range table.Entries() {
+		partitions = append(partitions, PartitionInfo{
+			Name:   partition.Name,
+			Type:   strings.TrimRight(string(partition.Type), "\x00"),
+			Start:  partition.Start,
+			Size:   partition.Size,
+			Status: strings.TrimRight(string(partition.Status), "\x00"),
+			ID:     partition.Id,
+		})
 	}
 
 	return partitions, nil
 }
 
+// Carpeta donde el servidor busca los discos para el catálogo
+var disksDir = "disks"
+
+// Función para configurar la carpeta de los discos (vacío conserva la carpeta por defecto)
+func SetDisksDir(dir string) {
+	if dir != "" {
+		disksDir = dir
+	}
+}
+
+// Información de un disco para el catálogo: datos del MBR, particiones (incluidas las lógicas) y montajes
+type DiskInfo struct {
+	Path         string          `json:"path"`
+	Size         int64           `json:"size"`
+	Signature    int32           `json:"signature"`
+	CreationDate string          `json:"creation_date"`
+	Fit          string          `json:"fit"`
+	Table        string          `json:"table"`
+	Partitions   []PartitionInfo `json:"partitions"`
+	MountIDs     []string        `json:"mount_ids"`
+	Error        string          `json:"error,omitempty"` // Motivo si el disco no se pudo leer por completo
+}
+
+// Función para armar el catálogo de discos: los discos de la carpeta de discos y los discos con
+// particiones montadas que estén fuera de ella. Cada disco aparece una sola vez aunque se conozca por varias rutas.
+func ListDisks() ([]DiskInfo, error) {
+	var paths []string
+	err := filepath.WalkDir(disksDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == disksDir {
+				return filepath.SkipDir // Todavía no se ha creado ningún disco en la carpeta
+			}
+			return err
+		}
+		if entry.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo recorrer la carpeta de discos %s: %v", disksDir, err)
+	}
+	for _, info := range diskMountInfos {
+		paths = append(paths, info.Path)
+	}
+
+	seen := make(map[string]bool)
+	disks := []DiskInfo{}
+	for _, path := range paths {
+		// Los archivos que no son discos (sin un MBR reconocible) no forman parte del catálogo
+		diskID, err := generateDiskID(path)
+		if err != nil || seen[diskID] {
+			continue
+		}
+		seen[diskID] = true
+		disks = append(disks, readDiskInfo(path, diskID))
+	}
+
+	sort.Slice(disks, func(a, b int) bool {
+		return disks[a].Path < disks[b].Path
+	})
+	return disks, nil
+}
+
+// Función para leer la información de un disco del catálogo
+func readDiskInfo(path string, diskID string) DiskInfo {
+	info := DiskInfo{Path: path, Partitions: []PartitionInfo{}, MountIDs: []string{}}
+	for _, partition := range mountedPartitions[diskID] {
+		info.MountIDs = append(info.MountIDs, partition.ID)
+	}
+	sort.Strings(info.MountIDs)
+
+	file, err := os.Open(path)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	defer file.Close()
+
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		// Discos con el formato anterior: se muestran con el motivo para que se migren
+		var legacy Structs.MRBv1
+		if Utilities.ReadObject(file, &legacy, 0) == nil && hasLegacyHeader(file) {
+			info.Size = int64(legacy.MbrSize)
+			info.Signature = legacy.Signature
+			info.CreationDate = strings.TrimRight(string(legacy.CreationDate[:]), "\x00")
+			info.Fit = strings.TrimRight(string(legacy.Fit[:]), "\x00")
+		}
+		info.Error = err.Error()
+		return info
+	}
+
+	var mbr Structs.MRB
+	if err := Utilities.ReadObject(file, &mbr, 0); err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.Size = table.DiskSize()
+	info.Signature = mbr.Signature
+	info.CreationDate = strings.TrimRight(string(mbr.CreationDate[:]), "\x00")
+	info.Fit = strings.TrimRight(string(mbr.Fit[:]), "\x00")
+	info.Table = table.Kind()
+
+	partitions, err := ListPartitions(path)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.Partitions = append(info.Partitions, partitions...)
+
+	// Agregar las particiones lógicas recorriendo la cadena de EBRs de la extendida
+	if extended, ok := table.Extended(); ok {
+		chain, err := ReadEBRChain(file, extended)
+		if err != nil {
+			info.Error = err.Error()
+		}
+		for _, logical := range chain {
+			if logical.EBR.PartSize <= 0 {
+				continue // EBR vacío
+			}
+			info.Partitions = append(info.Partitions, PartitionInfo{
+				Name:   strings.TrimRight(string(logical.EBR.PartName[:]), "\x00"),
+				Type:   "l",
+				Start:  logical.EBR.PartStart,
+				Size:   logical.EBR.PartSize,
+				Status: strings.TrimRight(string(logical.EBR.PartMount), "\x00"),
+				ID:     strings.TrimRight(string(logical.EBR.PartId[:]), "\x00"),
+			})
+		}
+	}
+	return info
+}
+
 // Estructura para representar una partición montada
 type MountedPartition struct {
-	Path     string
-	Name     string
-	ID       string
-	Status   byte // 0: no montada, 1: montada
-	LoggedIn bool // true: usuario ha iniciado sesión, false: no ha iniciado sesión
+	Path     string `json:"path"`
+	Name     string `json:"name"`
+	ID       string `json:"id"`
+	Status   byte   `json:"status"` // 0: no montada, 1: montada
+	LoggedIn bool   `json:"-"`      // true: usuario ha iniciado sesión, false: no ha iniciado sesión (no se guarda en el registro)
+	User     string `json:"-"`      // Usuario de la sesión y sus IDs de usuario y grupo en users.txt
+	UID      int32  `json:"-"`
+	GID      int32  `json:"-"`
 }
 
 // Mapa para almacenar las particiones montadas, organizadas por disco
@@ -122,18 +256,29 @@ func GetMountedPartitions() map[string][]MountedPartition {
 	return mountedPartitions
 }
 
-// Función para marcar una partición como logueada
-func MarkPartitionAsLoggedIn(id string) {
-	for diskID, partitions := range mountedPartitions {
-		for i, partition := range partitions {
-			if partition.ID == id {
-				mountedPartitions[diskID][i].LoggedIn = true
-				fmt.Printf("Partición con ID %s marcada como logueada.\n", id)
-				return
+// Función para marcar una partición como logueada, guardando el usuario de la sesión
+func MarkPartitionAsLoggedIn(id string, user string, uid int32, gid int32) {
+	if diskID, index, found := findMountedPartition(id); found {
+		mountedPartitions[diskID][index].LoggedIn = true
+		mountedPartitions[diskID][index].User = user
+		mountedPartitions[diskID][index].UID = uid
+		mountedPartitions[diskID][index].GID = gid
+		fmt.Printf("Partición con ID %s marcada como logueada.\n", id)
+		return
+	}
+	fmt.Printf("No se encontró la partición con ID %s para marcarla como logueada.\n", id)
+}
+
+// Función para obtener la partición en la que hay una sesión iniciada
+func GetLoggedInPartition() (MountedPartition, bool) {
+	for _, partitions := range mountedPartitions {
+		for _, partition := range partitions {
+			if partition.LoggedIn {
+				return partition, true
 			}
 		}
 	}
-	fmt.Printf("No se encontró la partición con ID %s para marcarla como logueada.\n", id)
+	return MountedPartition{}, false
 }
 
 // Función para limpiar las particiones montadas
@@ -141,13 +286,307 @@ func CleanMountedPartitions() {
 	mountedPartitions = make(map[string][]MountedPartition)
 }
 
-// Función Mkdisk optimizada para escribir bloques de ceros
-func Mkdisk(size int, fit string, unit string, path string) {
+// Ruta del archivo JSON donde se guarda la tabla de particiones montadas entre reinicios
+var mountRegistryPath = "mount_registry.json"
+
+// Estructura del registro de montajes guardado en disco
+type mountRegistry struct {
+	Partitions []MountedPartition        `json:"partitions"`
+	Disks      map[string]*diskMountInfo `json:"disks"` // Letra y correlativo asignados a cada disco
+}
+
+// Función para configurar la ruta del registro de montajes (vacío conserva la ruta por defecto)
+func SetMountRegistryPath(path string) {
+	if path != "" {
+		mountRegistryPath = path
+	}
+}
+
+// Función para guardar la tabla de particiones montadas en el registro
+func SaveMountRegistry() error {
+	registry := mountRegistry{Disks: diskMountInfos}
+	for _, partitions := range mountedPartitions {
+		registry.Partitions = append(registry.Partitions, partitions...)
+	}
+	// Orden estable para que el archivo no cambie sin motivo
+	sort.Slice(registry.Partitions, func(a, b int) bool {
+		return registry.Partitions[a].ID < registry.Partitions[b].ID
+	})
+
+	data, err := json.MarshalIndent(registry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error al serializar el registro de montajes: %v", err)
+	}
+
+	if dir := filepath.Dir(mountRegistryPath); dir != "" {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return fmt.Errorf("Error al crear la carpeta del registro de montajes: %v", err)
+		}
+	}
+
+	// Escribir primero a un archivo temporal para no dejar el registro a medias
+	tempPath := mountRegistryPath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("Error al escribir el registro de montajes: %v", err)
+	}
+	if err := os.Rename(tempPath, mountRegistryPath); err != nil {
+		return fmt.Errorf("Error al reemplazar el registro de montajes: %v", err)
+	}
+	return nil
+}
+
+// Función para guardar el registro mostrando el error sin interrumpir el comando que lo llamó
+func persistMountRegistry() {
+	if err := SaveMountRegistry(); err != nil {
+		fmt.Println("Advertencia:", err)
+	}
+}
+
+// Función para restaurar la tabla de particiones montadas desde el registro al iniciar el servidor.
+// Cada entrada se concilia con el disco: si el archivo del disco ya no existe, si la partición
+// ya no está o si en el disco no aparece montada con el mismo ID, la entrada se descarta.
+// Las sesiones (login) no se restauran.
+func LoadMountRegistry() error {
+	data, err := os.ReadFile(mountRegistryPath)
+	if os.IsNotExist(err) {
+		return nil // Primer inicio, no hay nada que restaurar
+	}
+	if err != nil {
+		return fmt.Errorf("Error al leer el registro de montajes: %v", err)
+	}
+
+	var registry mountRegistry
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return fmt.Errorf("Error al interpretar el registro de montajes: %v", err)
+	}
+
+	mountedPartitions = make(map[string][]MountedPartition)
+	discarded := 0
+	for _, entry := range registry.Partitions {
+		if reason := validateRegistryEntry(entry); reason != "" {
+			fmt.Printf("Registro de montajes: se descarta %s (%s): %s\n", entry.ID, entry.Path, reason)
+			discarded++
+			continue
+		}
+
+		diskID, err := generateDiskID(entry.Path)
+		if err != nil {
+			fmt.Printf("Registro de montajes: se descarta %s (%s): %v\n", entry.ID, entry.Path, err)
+			discarded++
+			continue
+		}
+
+		entry.Status = '1'
+		entry.LoggedIn = false
+		mountedPartitions[diskID] = append(mountedPartitions[diskID], entry)
+	}
+
+	// Restaurar las letras y correlativos de los discos que todavía existen. El ID de cada disco se
+	// vuelve a calcular con su firma (los registros anteriores usaban la ruta como ID)
+	diskMountInfos = make(map[string]*diskMountInfo)
+	for registryID, info := range registry.Disks {
+		if info == nil {
+			continue
+		}
+		diskID, err := generateDiskID(info.Path)
+		if err != nil {
+			fmt.Printf("Registro de montajes: se libera la letra %s del disco %s: %v\n", info.Letter, info.Path, err)
+			discarded++
+			continue
+		}
+		if diskID != registryID {
+			discarded++ // Reescribir el registro con el ID nuevo
+		}
+		diskMountInfos[diskID] = info
+	}
+	restoreMountInfosFromPartitions()
+
+	fmt.Printf("Registro de montajes restaurado: %d particiones montadas, %d entradas descartadas\n", len(GetMountedIDs()), discarded)
+
+	// Reescribir el registro sin las entradas descartadas
+	if discarded > 0 {
+		return SaveMountRegistry()
+	}
+	return nil
+}
+
+// Función para verificar una entrada del registro contra el disco; devuelve el motivo si no es válida
+func validateRegistryEntry(entry MountedPartition) string {
+	if _, err := os.Stat(entry.Path); err != nil {
+		return "el disco ya no existe"
+	}
+
+	file, err := Utilities.OpenFile(entry.Path)
+	if err != nil {
+		return "no se pudo abrir el disco"
+	}
+	defer file.Close()
+
+	partition, err := FindPartitionByID(file, entry.ID)
+	if err != nil {
+		return "la partición ya no tiene este ID en el disco"
+	}
+	if !SamePartitionKey(partition.Name, entry.Name) {
+		return "el ID pertenece a otra partición"
+	}
+	if partition.Status != '1' {
+		return "la partición no está montada en el disco"
+	}
+	return ""
+}
+
+// Función para saber si un ID pertenece a una partición montada en la sesión actual
+func isMountedID(id string) bool {
+	_, found := GetMountedPartition(id)
+	return found
+}
+
+// Función para obtener los IDs de todas las particiones montadas
+func GetMountedIDs() []string {
+	var ids []string
+	for _, partitions := range mountedPartitions {
+		for _, partition := range partitions {
+			ids = append(ids, partition.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Prefijo de los IDs de montaje: por defecto los últimos dos dígitos del carnet 202401234
+var mountIDPrefix = "34"
+
+// Estructura con la asignación de IDs de un disco: su letra y el último correlativo usado
+type diskMountInfo struct {
+	Path        string `json:"path"`
+	Letter      string `json:"letter"`
+	Correlative int    `json:"correlative"`
+}
+
+// Letra y correlativo de cada disco montado en la sesión, organizados por disco
+var diskMountInfos = make(map[string]*diskMountInfo)
+
+// Función para configurar el prefijo de los IDs de montaje (vacío conserva el prefijo por defecto)
+func SetMountIDPrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	for _, c := range prefix {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return fmt.Errorf("el prefijo de los IDs de montaje solo puede contener letras y números: %q", prefix)
+		}
+	}
+	// Debe quedar espacio para al menos dos dígitos de correlativo y la letra del disco
+	if len(prefix)+3 > len(Structs.Partition{}.Id) {
+		return fmt.Errorf("el prefijo de los IDs de montaje es demasiado largo (máximo %d caracteres)", len(Structs.Partition{}.Id)-3)
+	}
+	mountIDPrefix = prefix
+	return nil
+}
+
+// Función para reconstruir las letras y correlativos a partir de las particiones montadas
+// (registros guardados antes de que existiera la asignación por disco)
+func restoreMountInfosFromPartitions() {
+	for diskID, partitions := range mountedPartitions {
+		for _, partition := range partitions {
+			letter := partition.ID[len(partition.ID)-1:]
+			correlative := 0
+			fmt.Sscanf(strings.TrimPrefix(partition.ID[:len(partition.ID)-1], mountIDPrefix), "%d", &correlative)
+
+			info, ok := diskMountInfos[diskID]
+			if !ok {
+				info = &diskMountInfo{Path: partition.Path, Letter: letter}
+				diskMountInfos[diskID] = info
+			}
+			if correlative > info.Correlative {
+				info.Correlative = correlative
+			}
+		}
+	}
+}
+
+// Función para asignar la letra de un disco: la primera letra que no usa otro disco de la sesión,
+// de modo que los discos reciben letras en el orden en que se montan y las conservan
+func assignDiskLetter(diskID string, path string) (*diskMountInfo, error) {
+	if info, ok := diskMountInfos[diskID]; ok {
+		return info, nil
+	}
+
+	used := make(map[string]bool)
+	for _, info := range diskMountInfos {
+		used[info.Letter] = true
+	}
+	for letter := 'a'; letter <= 'z'; letter++ {
+		if !used[string(letter)] {
+			info := &diskMountInfo{Path: path, Letter: string(letter)}
+			diskMountInfos[diskID] = info
+			return info, nil
+		}
+	}
+	return nil, fmt.Errorf("no hay letras disponibles para más discos")
+}
+
+// Función para generar el siguiente ID de montaje de un disco: prefijo + correlativo del disco + letra
+func nextMountID(diskID string, path string) (string, error) {
+	info, err := assignDiskLetter(diskID, path)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		info.Correlative++
+		id := fmt.Sprintf("%s%d%s", mountIDPrefix, info.Correlative, info.Letter)
+		if len(id) > len(Structs.Partition{}.Id) {
+			return "", fmt.Errorf("el ID %s excede los %d caracteres permitidos", id, len(Structs.Partition{}.Id))
+		}
+		if !isMountedID(id) {
+			return id, nil
+		}
+	}
+}
+
+// Función para escribir todo el disco con bloques de ceros
+func writeZeroImage(file *os.File, size int64) error {
+	// Optimización: Escribir grandes bloques de ceros
+	blockSize := int64(1024 * 1024)      // Bloques de 1MB
+	zeroBlock := make([]byte, blockSize) // Crear un bloque de ceros
+
+	remainingSize := size
+
+	for remainingSize > 0 {
+		if remainingSize < blockSize {
+			// Escribe lo que queda si es menor que el tamaño del bloque
+			zeroBlock = make([]byte, remainingSize)
+		}
+		if _, err := file.Write(zeroBlock); err != nil {
+			return fmt.Errorf("error escribiendo ceros: %v", err)
+		}
+		remainingSize -= blockSize
+	}
+	return nil
+}
+
+// Función Mkdisk, el disco se asigna con ceros, de forma dispersa (sparse) o reservando el espacio
+func Mkdisk(size int, fit string, unit string, path string, table string, alloc string) {
 	fmt.Println("======INICIO MKDISK======")
 	fmt.Println("Size:", size)
 	fmt.Println("Fit:", fit)
 	fmt.Println("Unit:", unit)
 	fmt.Println("Path:", path)
+	fmt.Println("Table:", table)
+	fmt.Println("Alloc:", alloc)
+
+	// Validar modo de asignación sparse - prealloc - zero
+	if alloc != "sparse" && alloc != "prealloc" && alloc != "zero" {
+		fmt.Println("Error: La asignación debe ser sparse, prealloc o zero")
+		return
+	}
+
+	// Validar tabla de particiones mbr - gpt
+	if table != "mbr" && table != "gpt" {
+		fmt.Println("Error: La tabla de particiones debe ser mbr o gpt")
+		return
+	}
 
 	// Validar fit bf/ff/wf
 	if fit != "bf" && fit != "wf" && fit != "ff" {
@@ -167,18 +606,18 @@ func Mkdisk(size int, fit string, unit string, path string) {
 		return
 	}
 
-	// Crear el archivo
-	err := Utilities.CreateFile(path)
+	// Asignar tamaño en bytes (validando que no se desborde)
+	diskSize, err := Utilities.ConvertToBytes(size, unit)
 	if err != nil {
 		fmt.Println("Error:", err)
 		return
 	}
 
-	// Asignar tamaño en bytes
-	if unit == "k" {
-		size = size * 1024
-	} else {
-		size = size * 1024 * 1024
+	// Crear el archivo
+	err = Utilities.CreateFile(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
 	}
 
 	// Abrir el archivo binario
@@ -186,30 +625,34 @@ func Mkdisk(size int, fit string, unit string, path string) {
 	if err != nil {
 		return
 	}
+	defer file.Close()
 
-	// Optimización: Escribir grandes bloques de ceros
-	blockSize := 1024 * 1024             // Bloques de 1MB
-	zeroBlock := make([]byte, blockSize) // Crear un bloque de ceros
-
-	remainingSize := size
-
-	for remainingSize > 0 {
-		if remainingSize < blockSize {
-			// Escribe lo que queda si es menor que el tamaño del bloque
-			zeroBlock = make([]byte, remainingSize)
-		}
-		_, err := file.Write(zeroBlock)
-		if err != nil {
-			fmt.Println("Error escribiendo ceros:", err)
-			return
+	// Asignar el espacio del disco en el host según el modo indicado
+	switch alloc {
+	case "sparse":
+		// Solo se fija el tamaño lógico, el host asigna los bloques cuando se escriben
+		err = file.Truncate(diskSize)
+	case "prealloc":
+		// Se reserva el espacio sin escribirlo; si el host no lo permite se escriben ceros
+		if err = Utilities.Preallocate(file, diskSize); err != nil {
+			fmt.Println("Advertencia: No se pudo reservar el espacio, se escribirán ceros:", err)
+			err = writeZeroImage(file, diskSize)
 		}
-		remainingSize -= blockSize
+	default:
+		err = writeZeroImage(file, diskSize)
+	}
+	if err != nil {
+		fmt.Println("Error al asignar el espacio del disco:", err)
+		file.Close()
+		os.Remove(path)
+		return
 	}
 
 	// Crear el MBR
 	var newMRB Structs.MRB
-	newMRB.MbrSize = int32(size)
-	newMRB.Signature = rand.Int31() // Número aleatorio rand.Int31() genera solo números no negativos
+	newMRB.Version = Structs.FormatVersion
+	newMRB.MbrSize = diskSize
+	newMRB.Signature = newDiskSignature(path) // Número aleatorio que no repite la firma de otro disco conocido
 	copy(newMRB.Fit[:], fit)
 
 	// Obtener la fecha actual en formato YYYY-MM-DD
@@ -217,27 +660,80 @@ func Mkdisk(size int, fit string, unit string, path string) {
 	formattedDate := currentTime.Format("2006-01-02")
 	copy(newMRB.CreationDate[:], formattedDate)
 
-	// Escribir el MBR en el archivo
-	if err := Utilities.WriteObject(file, newMRB, 0); err != nil {
+	// Escribir el MBR en el archivo (en GPT, el MBR protector y el encabezado GPT)
+	if table == "gpt" {
+		if err := initGPT(file, &newMRB); err != nil {
+			fmt.Println("Error:", err)
+			file.Close()
+			os.Remove(path)
+			return
+		}
+	} else if err := Utilities.WriteObject(file, newMRB, 0); err != nil {
+		fmt.Println("Error: No se pudo escribir el MBR:", err)
+		file.Close()
+		os.Remove(path)
 		return
 	}
 
-	// Leer el archivo y verificar el MBR
-	var TempMBR Structs.MRB
-	if err := Utilities.ReadObject(file, &TempMBR, 0); err != nil {
+	// Leer el archivo y verificar la tabla de particiones
+	TempTable, err := ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error:", err)
 		return
 	}
 
-	// Imprimir el MBR
-	Structs.PrintMBR(TempMBR)
+	// Imprimir la tabla de particiones
+	TempTable.Print()
 
-	// Cerrar el archivo
-	defer file.Close()
+	// Mostrar el tamaño lógico del disco y lo que ocupa realmente en el host
+	if usage, err := Utilities.HostUsage(file); err == nil {
+		fmt.Printf("Tamaño lógico: %d bytes, uso en el host: %d bytes\n", diskSize, usage)
+	}
 
 	fmt.Println("======FIN MKDISK======")
 }
 
-func Fdisk(size int, path string, name string, unit string, type_ string, fit string) {
+// Función para eliminar un disco (rmdisk)
+func Rmdisk(path string) error {
+	fmt.Println("======INICIO RMDISK======")
+	fmt.Println("Path:", path)
+
+	// Validar que el disco exista
+	if _, err := os.Stat(path); err != nil {
+		fmt.Println("Error: El disco no existe:", path)
+		return fmt.Errorf("el disco %s no existe", path)
+	}
+
+	// No se puede eliminar un disco con particiones montadas. Un disco que no se puede leer no tiene
+	// ID, pero tampoco puede tener particiones montadas
+	diskID, _ := generateDiskID(path)
+	if partitions := mountedPartitions[diskID]; len(partitions) > 0 {
+		var ids []string
+		for _, partition := range partitions {
+			ids = append(ids, partition.ID)
+		}
+		fmt.Println("Error: El disco tiene particiones montadas:", strings.Join(ids, ", "))
+		return fmt.Errorf("el disco tiene particiones montadas (%s), desmóntelas antes de eliminarlo", strings.Join(ids, ", "))
+	}
+
+	// Eliminar el archivo del disco
+	if err := os.Remove(path); err != nil {
+		fmt.Println("Error al eliminar el disco:", err)
+		return fmt.Errorf("no se pudo eliminar el disco: %v", err)
+	}
+
+	// Liberar la letra asignada al disco y actualizar el registro de montajes
+	if _, ok := diskMountInfos[diskID]; ok {
+		delete(diskMountInfos, diskID)
+		persistMountRegistry()
+	}
+
+	fmt.Println("Disco eliminado exitosamente.")
+	fmt.Println("======FIN RMDISK======")
+	return nil
+}
+
+func Fdisk(size int, path string, name string, unit string, type_ string, fit string) error {
 	fmt.Println("======Start FDISK======")
 	fmt.Println("Size:", size)
 	fmt.Println("Path:", path)
@@ -246,756 +742,3253 @@ func Fdisk(size int, path string, name string, unit string, type_ string, fit st
 	fmt.Println("Type:", type_)
 	fmt.Println("Fit:", fit)
 
-	// Validar fit (b/w/f)
-	if fit != "b" && fit != "f" && fit != "w" {
+	// Validar fit (b/w/f), vacío significa usar el ajuste del disco
+	if fit != "" && fit != "b" && fit != "f" && fit != "w" {
 		fmt.Println("Error: Fit must be 'b', 'f', or 'w'")
-		return
+		return fmt.Errorf("el ajuste debe ser 'b', 'f' o 'w'")
 	}
 
 	// Validar size > 0
 	if size <= 0 {
 		fmt.Println("Error: Size must be greater than 0")
-		return
+		return fmt.Errorf("el tamaño debe ser mayor a 0")
 	}
 
 	// Validar unit (b/k/m)
 	if unit != "b" && unit != "k" && unit != "m" {
 		fmt.Println("Error: Unit must be 'b', 'k', or 'm'")
-		return
+		return fmt.Errorf("la unidad debe ser 'b', 'k' o 'm'")
 	}
 
-	// Ajustar el tamaño en bytes
-	if unit == "k" {
-		size = size * 1024
-	} else if unit == "m" {
-		size = size * 1024 * 1024
+	// Ajustar el tamaño en bytes (validando que no se desborde)
+	sizeBytes, err := Utilities.ConvertToBytes(size, unit)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
 	}
 
 	// Abrir el archivo binario en la ruta proporcionada
 	file, err := Utilities.OpenFile(path)
 	if err != nil {
 		fmt.Println("Error: Could not open file at path:", path)
-		return
+		return err
 	}
+	defer file.Close()
 
-	var TempMBR Structs.MRB
-	// Leer el objeto desde el archivo binario
-	if err := Utilities.ReadObject(file, &TempMBR, 0); err != nil {
-		fmt.Println("Error: Could not read MBR from file")
-		return
+	// Leer la tabla de particiones (MBR o GPT)
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
 	}
 
-	// Imprimir el objeto MBR
-	Structs.PrintMBR(TempMBR)
+	// Imprimir la tabla de particiones
+	table.Print()
 
 	fmt.Println("-------------")
 
-	// Validaciones de las particiones
-	var primaryCount, extendedCount, totalPartitions int
-	var usedSpace int32 = 0
+	// El nombre debe caber completo en la tabla y no puede repetirse en el disco, porque las
+	// particiones se buscan por nombre (sin distinguir mayúsculas) en el resto de comandos
+	if err := validatePartitionName(file, table, name, type_); err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
 
-	for i := 0; i < 4; i++ {
-		if TempMBR.Partitions[i].Size != 0 {
-			totalPartitions++
-			usedSpace += TempMBR.Partitions[i].Size
+	// Validaciones de las particiones
+	entries := table.Entries()
+	extended, hasExtended := table.Extended()
 
-			if TempMBR.Partitions[i].Type[0] == 'p' {
-				primaryCount++
-			} else if TempMBR.Partitions[i].Type[0] == 'e' {
-				extendedCount++
-			}
+	// Manejar la creación de particiones lógicas dentro de una partición extendida
+	if type_ == "l" {
+		// Validar que no se pueda crear una partición lógica sin una extendida
+		if !hasExtended {
+			fmt.Println("Error: No se puede crear una partición lógica sin una partición extendida.")
+			return fmt.Errorf("no se puede crear una partición lógica sin una partición extendida")
 		}
-	}
 
-	// Validar que no se exceda el número máximo de particiones primarias y extendidas
-	if totalPartitions >= 4 {
-		fmt.Println("Error: No se pueden crear más de 4 particiones primarias o extendidas en total.")
-		return
-	}
+		// Si no se indicó un ajuste se usa el ajuste de la partición extendida
+		if fit == "" {
+			fit = string(NormalizeFit(string(extended.Fit[:])))
+		}
 
-	// Validar que solo haya una partición extendida
-	if type_ == "e" && extendedCount > 0 {
-		fmt.Println("Error: Solo se permite una partición extendida por disco.")
-		return
-	}
+		if err := CreateLogicalPartition(file, extended, sizeBytes, name, fit[0]); err != nil {
+			fmt.Println("Error:", err)
+			return err
+		}
 
-	// Validar que no se pueda crear una partición lógica sin una extendida
-	if type_ == "l" && extendedCount == 0 {
-		fmt.Println("Error: No se puede crear una partición lógica sin una partición extendida.")
-		return
+		// Imprimir todos los EBRs en la partición extendida
+		fmt.Println("Imprimiendo todos los EBRs en la partición extendida:")
+		PrintEBRChain(file, extended)
+		fmt.Println("")
+		fmt.Println("======FIN FDISK======")
+		fmt.Println("")
+		return nil
 	}
 
-	// Validar que el tamaño de la nueva partición no exceda el tamaño del disco
-	if usedSpace+int32(size) > TempMBR.MbrSize {
-		fmt.Println("Error: No hay suficiente espacio en el disco para crear esta partición.")
-		return
+	// Si no se indicó un ajuste se usa el ajuste del disco definido en mkdisk
+	if fit == "" {
+		fit = string(table.DiskFit())
 	}
 
-	// Determinar la posición de inicio de la nueva partición
-	var gap int32 = int32(binary.Size(TempMBR))
-	if totalPartitions > 0 {
-		gap = TempMBR.Partitions[totalPartitions-1].Start + TempMBR.Partitions[totalPartitions-1].Size
+	// Validar que no se exceda el número máximo de entradas de la tabla
+	if len(entries) >= table.Capacity() {
+		fmt.Printf("Error: No se pueden crear más de %d particiones en la tabla %s.\n", table.Capacity(), strings.ToUpper(table.Kind()))
+		return fmt.Errorf("no se pueden crear más de %d particiones en la tabla %s", table.Capacity(), strings.ToUpper(table.Kind()))
 	}
 
-	// Encontrar una posición vacía para la nueva partición
-	for i := 0; i < 4; i++ {
-		if TempMBR.Partitions[i].Size == 0 {
-			if type_ == "p" || type_ == "e" {
-				// Crear partición primaria o extendida
-				TempMBR.Partitions[i].Size = int32(size)
-				TempMBR.Partitions[i].Start = gap
-				copy(TempMBR.Partitions[i].Name[:], name)
-				copy(TempMBR.Partitions[i].Fit[:], fit)
-				copy(TempMBR.Partitions[i].Status[:], "0")
-				copy(TempMBR.Partitions[i].Type[:], type_)
-				TempMBR.Partitions[i].Correlative = int32(totalPartitions + 1)
-
-				if type_ == "e" {
-					// Inicializar el primer EBR en la partición extendida
-					ebrStart := gap // El primer EBR se coloca al inicio de la partición extendida
-					ebr := Structs.EBR{
-						PartFit:   fit[0],
-						PartStart: ebrStart,
-						PartSize:  0,
-						PartNext:  -1,
-					}
-					copy(ebr.PartName[:], "")
-					Utilities.WriteObject(file, ebr, int64(ebrStart))
-				}
-
-				break
-			}
-		}
+	// Las particiones extendidas solo existen en discos MBR
+	if type_ == "e" && table.Kind() == "gpt" {
+		fmt.Println("Error: Los discos GPT no admiten particiones extendidas.")
+		return fmt.Errorf("los discos GPT no admiten particiones extendidas")
 	}
 
-	// Manejar la creación de particiones lógicas dentro de una partición extendida
-	if type_ == "l" {
-		for i := 0; i < 4; i++ {
-			if TempMBR.Partitions[i].Type[0] == 'e' {
-				ebrPos := TempMBR.Partitions[i].Start
-				var ebr Structs.EBR
-				for {
-					Utilities.ReadObject(file, &ebr, int64(ebrPos))
-					if ebr.PartNext == -1 {
-						break
-					}
-					ebrPos = ebr.PartNext
-				}
+	// Validar que solo haya una partición extendida
+	if type_ == "e" && hasExtended {
+		fmt.Println("Error: Solo se permite una partición extendida por disco.")
+		return fmt.Errorf("solo se permite una partición extendida por disco")
+	}
 
-				// Calcular la posición de inicio de la nueva partición lógica
-				newEBRPos := ebr.PartStart + ebr.PartSize                    // El nuevo EBR se coloca después de la partición lógica anterior
-				logicalPartitionStart := newEBRPos + int32(binary.Size(ebr)) // El inicio de la partición lógica es justo después del EBR
+	// El tamaño de una extendida debe alcanzar al menos para su primer EBR
+	if type_ == "e" && sizeBytes <= int64(binary.Size(Structs.EBR{})) {
+		fmt.Println("Error: La partición extendida es demasiado pequeña para contener un EBR.")
+		return fmt.Errorf("la partición extendida es demasiado pequeña para contener un EBR")
+	}
 
-				// Ajustar el siguiente EBR
-				ebr.PartNext = newEBRPos
-				Utilities.WriteObject(file, ebr, int64(ebrPos))
+	// Determinar la posición de inicio de la nueva partición aplicando el ajuste sobre los espacios libres
+	space, ok := SelectFreeSpace(GetFreeSpaces(table), sizeBytes, fit[0])
+	if !ok {
+		fmt.Println("Error: No hay un espacio libre contiguo suficiente en el disco para crear esta partición.")
+		return fmt.Errorf("no hay un espacio libre contiguo suficiente en el disco para crear esta partición")
+	}
+	gap := space.Start
+	fmt.Printf("Espacio libre seleccionado (ajuste %s): inicio %d, tamaño %d\n", fit, space.Start, space.Size)
 
-				// Crear y escribir el nuevo EBR
-				newEBR := Structs.EBR{
-					PartFit:   fit[0],
-					PartStart: logicalPartitionStart,
-					PartSize:  int32(size),
-					PartNext:  -1,
-				}
-				copy(newEBR.PartName[:], name)
-				Utilities.WriteObject(file, newEBR, int64(newEBRPos))
-
-				// Imprimir el nuevo EBR creado
-				fmt.Println("Nuevo EBR creado:")
-				Structs.PrintEBR(newEBR)
-				fmt.Println("")
-
-				// Imprimir todos los EBRs en la partición extendida
-				fmt.Println("Imprimiendo todos los EBRs en la partición extendida:")
-				ebrPos = TempMBR.Partitions[i].Start
-				for {
-					err := Utilities.ReadObject(file, &ebr, int64(ebrPos))
-					if err != nil {
-						fmt.Println("Error al leer EBR:", err)
-						break
-					}
-					Structs.PrintEBR(ebr)
-					if ebr.PartNext == -1 {
-						break
-					}
-					ebrPos = ebr.PartNext
-				}
+	// Agregar la partición primaria o extendida en una entrada vacía de la tabla
+	if _, err := table.Add(name, type_[0], fit[0], gap, sizeBytes); err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
 
-				break
-			}
+	if type_ == "e" {
+		// Inicializar el primer EBR (vacío) al inicio de la partición extendida
+		ebr := Structs.EBR{
+			PartFit:   fit[0],
+			PartStart: gap,
+			PartSize:  0,
+			PartNext:  -1,
 		}
-		fmt.Println("")
+		Utilities.WriteObject(file, ebr, gap)
 	}
 
-	// Sobrescribir el MBR
-	if err := Utilities.WriteObject(file, TempMBR, 0); err != nil {
-		fmt.Println("Error: Could not write MBR to file")
-		return
+	// Sobrescribir la tabla de particiones
+	if err := table.Write(file); err != nil {
+		fmt.Println("Error: Could not write partition table to file")
+		return err
 	}
 
-	var TempMBR2 Structs.MRB
-	// Leer el objeto nuevamente para verificar
-	if err := Utilities.ReadObject(file, &TempMBR2, 0); err != nil {
-		fmt.Println("Error: Could not read MBR from file after writing")
-		return
+	// Leer la tabla nuevamente para verificar
+	updated, err := ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error: Could not read partition table from file after writing")
+		return err
 	}
 
-	// Imprimir el objeto MBR actualizado
-	Structs.PrintMBR(TempMBR2)
-
-	// Cerrar el archivo binario
-	defer file.Close()
+	// Imprimir la tabla de particiones actualizada
+	updated.Print()
 
 	fmt.Println("======FIN FDISK======")
 	fmt.Println("")
-
+	return nil
 }
 
-// Función para eliminar particiones
-func DeletePartition(path string, name string, delete_ string) {
-	fmt.Println("======Start DELETE PARTITION======")
-	fmt.Println("Path:", path)
-	fmt.Println("Name:", name)
-	fmt.Println("Delete type:", delete_)
+// Estructura para representar un espacio libre dentro del disco
+type FreeSpace struct {
+	Start int64
+	Size  int64
+}
 
-	// Abrir el archivo binario en la ruta proporcionada
-	file, err := Utilities.OpenFile(path)
-	if err != nil {
-		fmt.Println("Error: Could not open file at path:", path)
-		return
+// Función para convertir un ajuste (bf/ff/wf o b/f/w) a su letra correspondiente
+func NormalizeFit(fit string) byte {
+	fit = strings.ToLower(strings.TrimRight(fit, "\x00"))
+	if fit == "" {
+		return 'f' // Primer ajuste por defecto, igual que en mkdisk
 	}
-
-	var TempMBR Structs.MRB
-	// Leer el objeto desde el archivo binario
-	if err := Utilities.ReadObject(file, &TempMBR, 0); err != nil {
-		fmt.Println("Error: Could not read MBR from file")
-		return
+	switch fit[0] {
+	case 'b', 'w':
+		return fit[0]
 	}
+	return 'f'
+}
 
-	// Buscar la partición por nombre
-	found := false
-	for i := 0; i < 4; i++ {
-		// Limpiar los caracteres nulos al final del nombre de la partición
-		partitionName := strings.TrimRight(string(TempMBR.Partitions[i].Name[:]), "\x00")
-		if partitionName == name {
-			found = true
-
-			// Si es una partición extendida, eliminar las particiones lógicas dentro de ella
-			if TempMBR.Partitions[i].Type[0] == 'e' {
-				fmt.Println("Eliminando particiones lógicas dentro de la partición extendida...")
-				ebrPos := TempMBR.Partitions[i].Start
-				var ebr Structs.EBR
-				for {
-					err := Utilities.ReadObject(file, &ebr, int64(ebrPos))
-					if err != nil {
-						fmt.Println("Error al leer EBR:", err)
-						break
-					}
-					// Detener el bucle si el EBR está vacío
-					if ebr.PartStart == 0 && ebr.PartSize == 0 {
-						fmt.Println("EBR vacío encontrado, deteniendo la búsqueda.")
-						break
-					}
-					// Depuración: Mostrar el EBR leído
-					fmt.Println("EBR leído antes de eliminar:")
-					Structs.PrintEBR(ebr)
-
-					// Eliminar partición lógica
-					if delete_ == "fast" {
-						ebr = Structs.EBR{}                             // Resetear el EBR manualmente
-						Utilities.WriteObject(file, ebr, int64(ebrPos)) // Sobrescribir el EBR reseteado
-					} else if delete_ == "full" {
-						Utilities.FillWithZeros(file, ebr.PartStart, ebr.PartSize)
-						ebr = Structs.EBR{}                             // Resetear el EBR manualmente
-						Utilities.WriteObject(file, ebr, int64(ebrPos)) // Sobrescribir el EBR reseteado
-					}
-
-					// Depuración: Mostrar el EBR después de eliminar
-					fmt.Println("EBR después de eliminar:")
-					Structs.PrintEBR(ebr)
-
-					if ebr.PartNext == -1 {
-						break
-					}
-					ebrPos = ebr.PartNext
-				}
-			}
-
-			// Proceder a eliminar la partición (extendida, primaria o lógica)
-			if delete_ == "fast" {
-				// Eliminar rápido: Resetear manualmente los campos de la partición
-				TempMBR.Partitions[i] = Structs.Partition{} // Resetear la partición manualmente
-				fmt.Println("Partición eliminada en modo Fast.")
-			} else if delete_ == "full" {
-				// Eliminar completamente: Resetear manualmente y sobrescribir con '\0'
-				start := TempMBR.Partitions[i].Start
-				size := TempMBR.Partitions[i].Size
-				TempMBR.Partitions[i] = Structs.Partition{} // Resetear la partición manualmente
-				// Escribir '\0' en el espacio de la partición en el disco
-				Utilities.FillWithZeros(file, start, size)
-				fmt.Println("Partición eliminada en modo Full.")
+// Función para obtener los espacios libres del disco (huecos entre la tabla de particiones, las particiones y el final del disco)
+func GetFreeSpaces(table PartitionTable) []FreeSpace {
+	// Ordenar las particiones por su posición física, no por el orden de las entradas de la tabla
+	used := table.Entries()
+	sort.Slice(used, func(a, b int) bool {
+		return used[a].Start < used[b].Start
+	})
 
-				// Leer y verificar si el área está llena de ceros
-				Utilities.VerifyZeros(file, start, size)
-			}
-			break
+	// Recorrer el disco desde el final de la tabla hasta el final del área disponible
+	var spaces []FreeSpace
+	cursor := table.FirstUsable()
+	for _, partition := range used {
+		if partition.Start > cursor {
+			spaces = append(spaces, FreeSpace{Start: cursor, Size: partition.Start - cursor})
 		}
-	}
-
-	if !found {
-		// Buscar particiones lógicas si no se encontró en el MBR
-		fmt.Println("Buscando en particiones lógicas dentro de las extendidas...")
-		for i := 0; i < 4; i++ {
-			if TempMBR.Partitions[i].Type[0] == 'e' { // Solo buscar dentro de particiones extendidas
-				ebrPos := TempMBR.Partitions[i].Start
-				var ebr Structs.EBR
-				for {
-					err := Utilities.ReadObject(file, &ebr, int64(ebrPos))
-					if err != nil {
-						fmt.Println("Error al leer EBR:", err)
-						break
-					}
-
-					// Depuración: Mostrar el EBR leído
-					fmt.Println("EBR leído:")
-					Structs.PrintEBR(ebr)
-
-					logicalName := strings.TrimRight(string(ebr.PartName[:]), "\x00")
-					if logicalName == name {
-						found = true
-						// Eliminar la partición lógica
-						if delete_ == "fast" {
-							ebr = Structs.EBR{}                             // Resetear el EBR manualmente
-							Utilities.WriteObject(file, ebr, int64(ebrPos)) // Sobrescribir el EBR reseteado
-							fmt.Println("Partición lógica eliminada en modo Fast.")
-						} else if delete_ == "full" {
-							Utilities.FillWithZeros(file, ebr.PartStart, ebr.PartSize)
-							ebr = Structs.EBR{}                             // Resetear el EBR manualmente
-							Utilities.WriteObject(file, ebr, int64(ebrPos)) // Sobrescribir el EBR reseteado
-							Utilities.VerifyZeros(file, ebr.PartStart, ebr.PartSize)
-							fmt.Println("Partición lógica eliminada en modo Full.")
-						}
-						break
-					}
-
-					if ebr.PartNext == -1 {
-						break
-					}
-					ebrPos = ebr.PartNext
-				}
-			}
-			if found {
-				break
-			}
+		if end := partition.Start + partition.Size; end > cursor {
+			cursor = end
 		}
 	}
-
-	if !found {
-		fmt.Println("Error: No se encontró la partición con el nombre:", name)
-		return
+	if table.LastUsable() > cursor {
+		spaces = append(spaces, FreeSpace{Start: cursor, Size: table.LastUsable() - cursor})
 	}
 
-	// Sobrescribir el MBR
-	if err := Utilities.WriteObject(file, TempMBR, 0); err != nil {
-		fmt.Println("Error: Could not write MBR to file")
-		return
-	}
+	return spaces
+}
 
-	// Leer el MBR actualizado y mostrarlo
-	fmt.Println("MBR actualizado después de la eliminación:")
-	Structs.PrintMBR(TempMBR)
+// Función para elegir un espacio libre según el ajuste: primer (f), mejor (b) o peor (w) ajuste
+func SelectFreeSpace(spaces []FreeSpace, size int64, fit byte) (FreeSpace, bool) {
+	var selected FreeSpace
+	found := false
 
-	// Si es una partición extendida, mostrar los EBRs actualizados
-	for i := 0; i < 4; i++ {
-		if TempMBR.Partitions[i].Type[0] == 'e' {
-			fmt.Println("Imprimiendo EBRs actualizados en la partición extendida:")
-			ebrPos := TempMBR.Partitions[i].Start
-			var ebr Structs.EBR
-			for {
-				err := Utilities.ReadObject(file, &ebr, int64(ebrPos))
-				if err != nil {
-					fmt.Println("Error al leer EBR:", err)
-					break
-				}
-				// Detener el bucle si el EBR está vacío
-				if ebr.PartStart == 0 && ebr.PartSize == 0 {
-					fmt.Println("EBR vacío encontrado, deteniendo la búsqueda.")
-					break
-				}
-				// Depuración: Imprimir cada EBR leído
-				fmt.Println("EBR leído después de actualización:")
-				Structs.PrintEBR(ebr)
-				if ebr.PartNext == -1 {
-					break
-				}
-				ebrPos = ebr.PartNext
+	for _, space := range spaces {
+		if space.Size < size {
+			continue
+		}
+		if !found {
+			selected = space
+			found = true
+			if fit == 'f' {
+				break // Primer ajuste: el primer espacio donde cabe
 			}
+			continue
+		}
+		if fit == 'b' && space.Size < selected.Size {
+			selected = space // Mejor ajuste: el espacio más pequeño donde cabe
+		} else if fit == 'w' && space.Size > selected.Size {
+			selected = space // Peor ajuste: el espacio más grande
 		}
 	}
 
-	// Cerrar el archivo binario
-	defer file.Close()
-
-	fmt.Println("======FIN DELETE PARTITION======")
+	return selected, found
 }
 
-func ModifyPartition(path string, name string, add int, unit string) error {
-	fmt.Println("======Start MODIFY PARTITION======")
-	// Abrir el archivo binario en la ruta proporcionada
-	file, err := Utilities.OpenFile(path)
-	if err != nil {
-		fmt.Println("Error: Could not open file at path:", path)
-		return err
-	}
-	defer file.Close()
+// Tabla de particiones de un disco: el MBR clásico (4 entradas y una extendida) o una tabla GPT
+// (más entradas, solo particiones primarias). Las operaciones sobre particiones primarias usan esta interfaz.
+type PartitionTable interface {
+	Kind() string                        // "mbr" o "gpt"
+	DiskSize() int64                     // Tamaño total del disco
+	DiskFit() byte                       // Ajuste por defecto del disco
+	FirstUsable() int64                  // Primer byte disponible para particiones
+	LastUsable() int64                   // Final del área disponible para particiones (exclusivo)
+	Capacity() int                       // Cantidad máxima de entradas
+	Entries() []PartitionLocation        // Entradas usadas (Index es la posición en la tabla)
+	Extended() (Structs.Partition, bool) // Partición extendida (solo MBR)
+	Add(name string, type_ byte, fit byte, start int64, size int64) (PartitionLocation, error)
+	Update(location PartitionLocation) // Guarda Start, Size, Status e Id en la entrada location.Index
+	Remove(index int)
+	Write(file *os.File) error
+	Print()
+}
 
-	// Leer el MBR
-	var TempMBR Structs.MRB
-	if err := Utilities.ReadObject(file, &TempMBR, 0); err != nil {
-		fmt.Println("Error: Could not read MBR from file")
-		return err
-	}
+// Firma que identifica un encabezado GPT
+const gptSignature = "EFI PART"
 
-	// Imprimir MBR antes de modificar
-	fmt.Println("MBR antes de la modificación:")
-	Structs.PrintMBR(TempMBR)
+// Cantidad de entradas del arreglo de particiones GPT
+const gptEntryCount = 128
 
-	// Buscar la partición por nombre
-	var foundPartition *Structs.Partition
-	var partitionType byte
+// Función para saber si un MBR es el MBR protector de un disco GPT
+func isProtectiveMBR(mbr Structs.MRB) bool {
+	return mbr.Partitions[0].Size > 0 && mbr.Partitions[0].Type[0] == 'g'
+}
 
-	// Revisar si la partición es primaria o extendida
-	for i := 0; i < 4; i++ {
-		partitionName := strings.TrimRight(string(TempMBR.Partitions[i].Name[:]), "\x00")
-		if partitionName == name {
-			foundPartition = &TempMBR.Partitions[i]
-			partitionType = TempMBR.Partitions[i].Type[0]
-			break
-		}
+// Función para leer la tabla de particiones de un disco (MBR o GPT)
+func ReadPartitionTable(file *os.File) (PartitionTable, error) {
+	var mbr Structs.MRB
+	if err := Utilities.ReadObject(file, &mbr, 0); err != nil {
+		return nil, fmt.Errorf("no se pudo leer el MBR: %v", err)
 	}
-
-	// Si no se encuentra en las primarias/extendidas, buscar en las particiones lógicas
-	if foundPartition == nil {
-		for i := 0; i < 4; i++ {
-			if TempMBR.Partitions[i].Type[0] == 'e' {
-				ebrPos := TempMBR.Partitions[i].Start
-				var ebr Structs.EBR
-				for {
-					if err := Utilities.ReadObject(file, &ebr, int64(ebrPos)); err != nil {
-						fmt.Println("Error al leer EBR:", err)
-						return err
-					}
-
-					ebrName := strings.TrimRight(string(ebr.PartName[:]), "\x00")
-					if ebrName == name {
-						partitionType = 'l' // Partición lógica
-						foundPartition = &Structs.Partition{
-							Start: ebr.PartStart,
-							Size:  ebr.PartSize,
-						}
-						break
-					}
-
-					// Continuar buscando el siguiente EBR
-					if ebr.PartNext == -1 {
-						break
-					}
-					ebrPos = ebr.PartNext
-				}
-				if foundPartition != nil {
-					break
-				}
-			}
+	if mbr.Version != Structs.FormatVersion {
+		if hasLegacyHeader(file) {
+			return nil, fmt.Errorf("el disco usa el formato anterior de 32 bits, actualícelo con el comando migrate")
 		}
+		return nil, fmt.Errorf("versión del formato del disco no soportada: %d", mbr.Version)
 	}
-
-	// Verificar si la partición fue encontrada
-	if foundPartition == nil {
-		fmt.Println("Error: No se encontró la partición con el nombre:", name)
-		return nil // Salir si no se encuentra la partición
+	if !isProtectiveMBR(mbr) {
+		return &mbrTable{mbr: mbr}, nil
 	}
 
-	// Convertir unidades a bytes
-	var addBytes int
-	if unit == "k" {
-		addBytes = add * 1024
-	} else if unit == "m" {
-		addBytes = add * 1024 * 1024
-	} else {
-		fmt.Println("Error: Unidad desconocida, debe ser 'k' o 'm'")
-		return nil // Salir si la unidad no es válida
+	table := &gptTable{protective: mbr}
+	headerStart := int64(binary.Size(mbr))
+	if err := Utilities.ReadObject(file, &table.header, headerStart); err != nil {
+		return nil, fmt.Errorf("no se pudo leer el encabezado GPT: %v", err)
+	}
+	if string(table.header.Signature[:]) != gptSignature {
+		return nil, fmt.Errorf("el encabezado GPT no es válido")
 	}
 
-	// Flag para saber si continuar o no
-	var shouldModify = true
-
-	// Comprobar si es posible agregar o quitar espacio
-	if add > 0 {
-		// Agregar espacio: verificar si hay suficiente espacio libre después de la partición
-		nextPartitionStart := foundPartition.Start + foundPartition.Size
-		if partitionType == 'l' {
-			// Para particiones lógicas, verificar con el siguiente EBR o el final de la partición extendida
-			for i := 0; i < 4; i++ {
-				if TempMBR.Partitions[i].Type[0] == 'e' {
-					extendedPartitionEnd := TempMBR.Partitions[i].Start + TempMBR.Partitions[i].Size
-					if nextPartitionStart+int32(addBytes) > extendedPartitionEnd {
-						fmt.Println("Error: No hay suficiente espacio libre dentro de la partición extendida")
-						shouldModify = false
-					}
-					break
-				}
-			}
-		} else {
-			// Para primarias o extendidas
-			if nextPartitionStart+int32(addBytes) > TempMBR.MbrSize {
-				fmt.Println("Error: No hay suficiente espacio libre después de la partición")
-				shouldModify = false
-			}
-		}
-	} else {
-		// Quitar espacio: verificar que no se reduzca el tamaño por debajo de 0
-		if foundPartition.Size+int32(addBytes) < 0 {
-			fmt.Println("Error: No es posible reducir la partición por debajo de 0")
-			shouldModify = false
+	table.entries = make([]Structs.GPTEntry, table.header.EntryCount)
+	for i := range table.entries {
+		position := table.header.EntriesStart + int64(i)*int64(table.header.EntrySize)
+		if err := Utilities.ReadObject(file, &table.entries[i], position); err != nil {
+			return nil, fmt.Errorf("no se pudo leer la entrada GPT %d: %v", i, err)
 		}
 	}
+	return table, nil
+}
 
-	// Solo modificar si no hay errores
-	if shouldModify {
-		foundPartition.Size += int32(addBytes)
-	} else {
-		fmt.Println("No se realizaron modificaciones debido a un error.")
-		return nil // Salir si hubo un error
+// Función para saber si un disco empieza con el encabezado del formato anterior (versión 1, tamaños de 32 bits)
+// En ese formato el disco empieza con su tamaño total en 32 bits en lugar de la versión. Solo se revisa el
+// encabezado; readLegacyDisk valida el resto del disco antes de migrarlo
+func hasLegacyHeader(file *os.File) bool {
+	var legacy Structs.MRBv1
+	if err := Utilities.ReadObject(file, &legacy, 0); err != nil {
+		return false
 	}
+	info, err := file.Stat()
+	return err == nil && int64(legacy.MbrSize) == info.Size()
+}
 
-	// Si es una partición lógica, sobrescribir el EBR
-	if partitionType == 'l' {
-		ebrPos := foundPartition.Start
-		var ebr Structs.EBR
-		if err := Utilities.ReadObject(file, &ebr, int64(ebrPos)); err != nil {
-			fmt.Println("Error al leer EBR:", err)
-			return err
-		}
+// Función para generar un identificador aleatorio de 16 bytes (GUID)
+func newGUID() [16]byte {
+	var guid [16]byte
+	rand.Read(guid[:])
+	guid[6] = (guid[6] & 0x0f) | 0x40 // Versión 4
+	guid[8] = (guid[8] & 0x3f) | 0x80 // Variante RFC 4122
+	return guid
+}
 
-		// Actualizar el tamaño en el EBR y escribirlo de nuevo
-		ebr.PartSize = foundPartition.Size
-		if err := Utilities.WriteObject(file, ebr, int64(ebrPos)); err != nil {
-			fmt.Println("Error al escribir el EBR actualizado:", err)
-			return err
-		}
+// Función para escribir un MBR protector y una tabla GPT vacía en un disco nuevo
+func initGPT(file *os.File, mbr *Structs.MRB) error {
+	mbrSize := int64(binary.Size(*mbr))
 
-		// Imprimir el EBR modificado
-		fmt.Println("EBR modificado:")
-		Structs.PrintEBR(ebr)
+	header := Structs.GPTHeader{
+		DiskGUID:     newGUID(),
+		DiskSize:     mbr.MbrSize,
+		EntriesStart: mbrSize + int64(binary.Size(Structs.GPTHeader{})),
+		EntryCount:   gptEntryCount,
+		EntrySize:    int32(binary.Size(Structs.GPTEntry{})),
+	}
+	copy(header.Signature[:], gptSignature)
+	header.FirstUsable = header.EntriesStart + int64(header.EntryCount)*int64(header.EntrySize)
+	header.LastUsable = mbr.MbrSize
+
+	if header.FirstUsable >= header.LastUsable {
+		return fmt.Errorf("el disco es demasiado pequeño para una tabla GPT (mínimo %d bytes)", header.FirstUsable+1)
+	}
+
+	// La única partición del MBR protector cubre todo el disco para que nadie lo use como MBR
+	mbr.Partitions[0] = Structs.Partition{
+		Start: mbrSize,
+		Size:  mbr.MbrSize - mbrSize,
 	}
+	copy(mbr.Partitions[0].Status[:], "0")
+	copy(mbr.Partitions[0].Type[:], "g")
+	copy(mbr.Partitions[0].Fit[:], mbr.Fit[:])
+	copy(mbr.Partitions[0].Name[:], "GPT")
 
-	// Sobrescribir el MBR actualizado
-	if err := Utilities.WriteObject(file, TempMBR, 0); err != nil {
-		fmt.Println("Error al escribir el MBR actualizado:", err)
+	if err := Utilities.WriteObject(file, *mbr, 0); err != nil {
 		return err
 	}
+	// El disco recién creado está lleno de ceros, las entradas ya están vacías
+	return Utilities.WriteObject(file, header, mbrSize)
+}
 
-	// Imprimir el MBR modificado
-	fmt.Println("MBR después de la modificación:")
-	Structs.PrintMBR(TempMBR)
+// Tabla de particiones MBR
+type mbrTable struct {
+	mbr Structs.MRB
+}
 
-	fmt.Println("======END MODIFY PARTITION======")
-	return nil
+func (t *mbrTable) Kind() string       { return "mbr" }
+func (t *mbrTable) DiskSize() int64    { return t.mbr.MbrSize }
+func (t *mbrTable) DiskFit() byte      { return NormalizeFit(string(t.mbr.Fit[:])) }
+func (t *mbrTable) FirstUsable() int64 { return int64(binary.Size(t.mbr)) }
+func (t *mbrTable) LastUsable() int64  { return t.mbr.MbrSize }
+func (t *mbrTable) Capacity() int      { return len(t.mbr.Partitions) }
+
+func (t *mbrTable) Entries() []PartitionLocation {
+	var entries []PartitionLocation
+	for i, partition := range t.mbr.Partitions {
+		if partition.Size == 0 {
+			continue
+		}
+		entries = append(entries, PartitionLocation{
+			Name:   strings.TrimRight(string(partition.Name[:]), "\x00"),
+			Type:   partition.Type[0],
+			Fit:    partition.Fit[0],
+			Start:  partition.Start,
+			Size:   partition.Size,
+			Status: partition.Status[0],
+			Id:     strings.TrimRight(string(partition.Id[:]), "\x00"),
+			Index:  i,
+		})
+	}
+	return entries
 }
 
-// Función para montar particiones
-func Mount(path string, name string) {
-	file, err := Utilities.OpenFile(path)
-	if err != nil {
-		fmt.Println("Error: No se pudo abrir el archivo en la ruta:", path)
-		return
+func (t *mbrTable) Extended() (Structs.Partition, bool) {
+	if index := extendedIndex(t.mbr); index != -1 {
+		return t.mbr.Partitions[index], true
 	}
-	defer file.Close()
+	return Structs.Partition{}, false
+}
 
-	var TempMBR Structs.MRB
-	if err := Utilities.ReadObject(file, &TempMBR, 0); err != nil {
-		fmt.Println("Error: No se pudo leer el MBR desde el archivo")
-		return
-	}
+func (t *mbrTable) Add(name string, type_ byte, fit byte, start int64, size int64) (PartitionLocation, error) {
+	used := len(t.Entries())
+	for i := range t.mbr.Partitions {
+		if t.mbr.Partitions[i].Size != 0 {
+			continue
+		}
+		partition := Structs.Partition{Start: start, Size: size, Correlative: int32(used + 1)}
+		copy(partition.Name[:], name)
+		partition.Fit[0] = fit
+		partition.Status[0] = '0'
+		partition.Type[0] = type_
+		t.mbr.Partitions[i] = partition
+		return PartitionLocation{Name: name, Type: type_, Fit: fit, Start: start, Size: size, Status: '0', Index: i}, nil
+	}
+	return PartitionLocation{}, fmt.Errorf("no se pueden crear más de %d particiones primarias o extendidas en total", t.Capacity())
+}
 
-	fmt.Printf("Buscando partición con nombre: '%s'\n", name)
+func (t *mbrTable) Update(location PartitionLocation) {
+	partition := &t.mbr.Partitions[location.Index]
+	partition.Start = location.Start
+	partition.Size = location.Size
+	partition.Status[0] = location.Status
+	partition.Id = [16]byte{}
+	copy(partition.Id[:], location.Id)
+}
 
-	partitionFound := false
-	var partition Structs.Partition
-	var partitionIndex int
+func (t *mbrTable) Remove(index int) {
+	t.mbr.Partitions[index] = Structs.Partition{}
+}
 
-	// Convertir el nombre a comparar a un arreglo de bytes de longitud fija
-	nameBytes := [16]byte{}
-	copy(nameBytes[:], []byte(name))
+func (t *mbrTable) Write(file *os.File) error {
+	return Utilities.WriteObject(file, t.mbr, 0)
+}
 
-	for i := 0; i < 4; i++ {
-		if TempMBR.Partitions[i].Type[0] == 'p' && bytes.Equal(TempMBR.Partitions[i].Name[:], nameBytes[:]) {
-			partition = TempMBR.Partitions[i]
-			partitionIndex = i
-			partitionFound = true
-			break
+func (t *mbrTable) Print() {
+	Structs.PrintMBR(t.mbr)
+}
+
+// Tabla de particiones GPT (con su MBR protector)
+type gptTable struct {
+	protective Structs.MRB
+	header     Structs.GPTHeader
+	entries    []Structs.GPTEntry
+}
+
+func (t *gptTable) Kind() string       { return "gpt" }
+func (t *gptTable) DiskSize() int64    { return t.protective.MbrSize }
+func (t *gptTable) DiskFit() byte      { return NormalizeFit(string(t.protective.Fit[:])) }
+func (t *gptTable) FirstUsable() int64 { return t.header.FirstUsable }
+func (t *gptTable) LastUsable() int64  { return t.header.LastUsable }
+func (t *gptTable) Capacity() int      { return len(t.entries) }
+
+func (t *gptTable) Entries() []PartitionLocation {
+	var entries []PartitionLocation
+	for i, entry := range t.entries {
+		if entry.Size == 0 {
+			continue
 		}
-	}
+		entries = append(entries, PartitionLocation{
+			Name:   strings.TrimRight(string(entry.Name[:]), "\x00"),
+			Type:   entry.Type[0],
+			Fit:    entry.Fit[0],
+			Start:  entry.Start,
+			Size:   entry.Size,
+			Status: entry.Status[0],
+			Id:     strings.TrimRight(string(entry.Id[:]), "\x00"),
+			Index:  i,
+		})
+	}
+	return entries
+}
 
-	if !partitionFound {
-		fmt.Println("Error: Partición no encontrada o no es una partición primaria")
-		return
-	}
+func (t *gptTable) Extended() (Structs.Partition, bool) {
+	return Structs.Partition{}, false
+}
 
-	// Verificar si la partición ya está montada
-	if partition.Status[0] == '1' {
-		fmt.Println("Error: La partición ya está montada")
-		return
+func (t *gptTable) Add(name string, type_ byte, fit byte, start int64, size int64) (PartitionLocation, error) {
+	if type_ != 'p' {
+		return PartitionLocation{}, fmt.Errorf("los discos GPT solo admiten particiones primarias")
+	}
+	if len(name) > len(Structs.GPTEntry{}.Name) {
+		return PartitionLocation{}, fmt.Errorf("el nombre de la partición excede los %d caracteres", len(Structs.GPTEntry{}.Name))
 	}
 
-	//fmt.Printf("Partición encontrada: '%s' en posición %d\n", string(partition.Name[:]), partitionIndex+1)
+	used := len(t.Entries())
+	for i := range t.entries {
+		if t.entries[i].Size != 0 {
+			continue
+		}
+		entry := Structs.GPTEntry{GUID: newGUID(), Start: int64(start), Size: int64(size), Correlative: int32(used + 1)}
+		copy(entry.Name[:], name)
+		entry.Fit[0] = fit
+		entry.Status[0] = '0'
+		entry.Type[0] = type_
+		t.entries[i] = entry
+		return PartitionLocation{Name: name, Type: type_, Fit: fit, Start: start, Size: size, Status: '0', Index: i}, nil
+	}
+	return PartitionLocation{}, fmt.Errorf("la tabla GPT ya tiene sus %d entradas ocupadas", t.Capacity())
+}
 
-	// Generar el ID de la partición
-	diskID := generateDiskID(path)
+func (t *gptTable) Update(location PartitionLocation) {
+	entry := &t.entries[location.Index]
+	entry.Start = location.Start
+	entry.Size = location.Size
+	entry.Status[0] = location.Status
+	entry.Id = [16]byte{}
+	copy(entry.Id[:], location.Id)
+}
 
-	// Verificar si ya se ha montado alguna partición de este disco
-	mountedPartitionsInDisk := mountedPartitions[diskID]
-	var letter byte
+func (t *gptTable) Remove(index int) {
+	t.entries[index] = Structs.GPTEntry{}
+}
 
-	if len(mountedPartitionsInDisk) == 0 {
-		// Es un nuevo disco, asignar la siguiente letra disponible
-		if len(mountedPartitions) == 0 {
-			letter = 'a'
-		} else {
-			lastDiskID := getLastDiskID()
-			lastLetter := mountedPartitions[lastDiskID][0].ID[len(mountedPartitions[lastDiskID][0].ID)-1]
-			letter = lastLetter + 1
+func (t *gptTable) Write(file *os.File) error {
+	for i, entry := range t.entries {
+		position := t.header.EntriesStart + int64(i)*int64(t.header.EntrySize)
+		if err := Utilities.WriteObject(file, entry, position); err != nil {
+			return err
 		}
-	} else {
-		// Utilizar la misma letra que las otras particiones montadas en el mismo disco
-		letter = mountedPartitionsInDisk[0].ID[len(mountedPartitionsInDisk[0].ID)-1]
 	}
+	return Utilities.WriteObject(file, t.header, int64(binary.Size(t.protective)))
+}
 
-	// Incrementar el número para esta partición
-	carnet := "202401234" // Cambiar su carnet aquí
-	lastTwoDigits := carnet[len(carnet)-2:]
-	partitionID := fmt.Sprintf("%s%d%c", lastTwoDigits, partitionIndex+1, letter)
-
-	// Actualizar el estado de la partición a montada y asignar el ID
-	partition.Status[0] = '1'
-	copy(partition.Id[:], partitionID)
-	TempMBR.Partitions[partitionIndex] = partition
-	mountedPartitions[diskID] = append(mountedPartitions[diskID], MountedPartition{
-		Path:   path,
-		Name:   name,
-		ID:     partitionID,
-		Status: '1',
-	})
-
-	// Escribir el MBR actualizado al archivo
-	if err := Utilities.WriteObject(file, TempMBR, 0); err != nil {
-		fmt.Println("Error: No se pudo sobrescribir el MBR en el archivo")
-		return
+func (t *gptTable) Print() {
+	fmt.Println(fmt.Sprintf("CreationDate: %s, fit: %s, size: %d (GPT)", string(t.protective.CreationDate[:]), string(t.protective.Fit[:]), t.protective.MbrSize))
+	Structs.PrintGPTHeader(t.header)
+	for _, entry := range t.entries {
+		if entry.Size > 0 {
+			Structs.PrintGPTEntry(entry)
+		}
 	}
+}
 
-	fmt.Printf("Partición montada con ID: %s\n", partitionID)
-
-	fmt.Println("")
-	// Imprimir el MBR actualizado
-	fmt.Println("MBR actualizado:")
-	Structs.PrintMBR(TempMBR)
-	fmt.Println("")
+// Función para obtener las entradas GPT y el encabezado (para los reportes)
+func GPTDetails(table PartitionTable) (Structs.MRB, Structs.GPTHeader, []Structs.GPTEntry, bool) {
+	gpt, ok := table.(*gptTable)
+	if !ok {
+		return Structs.MRB{}, Structs.GPTHeader{}, nil, false
+	}
+	return gpt.protective, gpt.header, gpt.entries, true
+}
 
-	// Imprimir las particiones montadas (solo estan mientras dure la sesion de la consola)
-	PrintMountedPartitions()
+// Estructura para representar un EBR junto con la posición donde está escrito en el disco
+type LogicalPartition struct {
+	Position int64 // Posición del EBR dentro del disco
+	EBR      Structs.EBR
 }
 
-func Unmount(id string) {
-	fmt.Println("Desmontando partición con ID:", id)
+// Función para leer la cadena de EBRs de una partición extendida, en el orden de la cadena
+func ReadEBRChain(file *os.File, extended Structs.Partition) ([]LogicalPartition, error) {
+	var chain []LogicalPartition
+	extendedEnd := extended.Start + extended.Size
 
-	// Buscar la partición montada por ID
-	var partitionFound *MountedPartition
-	var diskID string
-	var partitionIndex int
+	visited := make(map[int64]bool)
 
-	for disk, partitions := range mountedPartitions {
-		for i, partition := range partitions {
-			if partition.ID == id {
-				partitionFound = &partitions[i]
-				diskID = disk
-				partitionIndex = i
-				break
-			}
+	ebrPos := extended.Start
+	for ebrPos != -1 {
+		// Un puntero fuera de la partición extendida no puede ser un EBR válido
+		if ebrPos < extended.Start || ebrPos >= extendedEnd {
+			return chain, fmt.Errorf("EBR fuera de la partición extendida en la posición %d", ebrPos)
 		}
-		if partitionFound != nil {
-			break
+		// Volver a un EBR ya visitado haría que la cadena no terminara nunca
+		if visited[ebrPos] {
+			return chain, fmt.Errorf("la cadena de EBRs tiene un ciclo en la posición %d", ebrPos)
 		}
-	}
+		visited[ebrPos] = true
 
-	// Si no se encuentra la partición, mostrar un error
-	if partitionFound == nil {
-		fmt.Println("Error: No se encontró una partición montada con el ID proporcionado:", id)
-		return
+		var ebr Structs.EBR
+		if err := Utilities.ReadObject(file, &ebr, ebrPos); err != nil {
+			return chain, err
+		}
+		chain = append(chain, LogicalPartition{Position: ebrPos, EBR: ebr})
+		ebrPos = ebr.PartNext
 	}
 
-	// Abrir el archivo del disco correspondiente
-	file, err := Utilities.OpenFile(partitionFound.Path)
+	return chain, nil
+}
+
+// Función para imprimir todos los EBRs de una partición extendida
+func PrintEBRChain(file *os.File, extended Structs.Partition) {
+	chain, err := ReadEBRChain(file, extended)
+	for _, logical := range chain {
+		Structs.PrintEBR(logical.EBR)
+	}
 	if err != nil {
-		fmt.Println("Error: No se pudo abrir el archivo en la ruta:", partitionFound.Path)
-		return
+		fmt.Println("Error al leer EBR:", err)
 	}
-	defer file.Close()
+}
 
-	// Leer el MBR
-	var TempMBR Structs.MRB
-	if err := Utilities.ReadObject(file, &TempMBR, 0); err != nil {
-		fmt.Println("Error: No se pudo leer el MBR desde el archivo")
-		return
+// Función para obtener los espacios libres dentro de una partición extendida
+// Cada partición lógica ocupa su EBR más sus datos; un EBR cabecera vacío se considera libre
+func GetLogicalFreeSpaces(extended Structs.Partition, chain []LogicalPartition) []FreeSpace {
+	var used []LogicalPartition
+	for _, logical := range chain {
+		if logical.EBR.PartSize > 0 {
+			used = append(used, logical)
+		}
 	}
+	sort.Slice(used, func(a, b int) bool {
+		return used[a].Position < used[b].Position
+	})
 
-	// Buscar la partición en el MBR utilizando el nombre
-	nameBytes := [16]byte{}
-	copy(nameBytes[:], []byte(partitionFound.Name))
-	partitionUpdated := false
-
-	for i := 0; i < 4; i++ {
-		if bytes.Equal(TempMBR.Partitions[i].Name[:], nameBytes[:]) {
-			// Cambiar el estado de la partición de montada ('1') a desmontada ('0')
-			TempMBR.Partitions[i].Status[0] = '0'
-			// Borrar el ID de la partición
-			copy(TempMBR.Partitions[i].Id[:], "")
-			partitionUpdated = true
-			break
+	var spaces []FreeSpace
+	cursor := extended.Start
+	for _, logical := range used {
+		if logical.Position > cursor {
+			spaces = append(spaces, FreeSpace{Start: cursor, Size: logical.Position - cursor})
 		}
+		if end := logical.EBR.PartStart + logical.EBR.PartSize; end > cursor {
+			cursor = end
+		}
+	}
+	if extendedEnd := extended.Start + extended.Size; extendedEnd > cursor {
+		spaces = append(spaces, FreeSpace{Start: cursor, Size: extendedEnd - cursor})
 	}
 
-	if !partitionUpdated {
-		fmt.Println("Error: No se pudo encontrar la partición en el MBR para desmontar")
-		return
+	return spaces
+}
+
+// Función para crear una partición lógica dentro de la partición extendida aplicando el ajuste
+func CreateLogicalPartition(file *os.File, extended Structs.Partition, size int64, name string, fit byte) error {
+	chain, err := ReadEBRChain(file, extended)
+	if err != nil {
+		return fmt.Errorf("no se pudo leer la cadena de EBRs: %v", err)
+	}
+	if len(chain) == 0 {
+		return fmt.Errorf("la partición extendida no tiene un EBR inicial")
 	}
 
-	// Sobrescribir el MBR actualizado al archivo
-	if err := Utilities.WriteObject(file, TempMBR, 0); err != nil {
-		fmt.Println("Error: No se pudo sobrescribir el MBR en el archivo")
-		return
+	// La partición lógica necesita espacio para su EBR y para sus datos
+	ebrSize := int64(binary.Size(Structs.EBR{}))
+	space, ok := SelectFreeSpace(GetLogicalFreeSpaces(extended, chain), ebrSize+size, fit)
+	if !ok {
+		return fmt.Errorf("no hay espacio suficiente dentro de la partición extendida para la partición lógica")
 	}
+	fmt.Printf("Espacio libre seleccionado en la extendida (ajuste %c): inicio %d, tamaño %d\n", fit, space.Start, space.Size)
 
-	// Eliminar la partición de la lista de particiones montadas
-	mountedPartitions[diskID] = append(mountedPartitions[diskID][:partitionIndex], mountedPartitions[diskID][partitionIndex+1:]...)
+	newEBR := Structs.EBR{
+		PartMount: '0',
+		PartFit:   fit,
+		PartStart: space.Start + ebrSize, // Los datos de la lógica van justo después de su EBR
+		PartSize:  size,
+		PartNext:  -1,
+	}
+	copy(newEBR.PartName[:], name)
 
-	// Si ya no hay particiones montadas en este disco, eliminar el disco de la lista
-	if len(mountedPartitions[diskID]) == 0 {
-		delete(mountedPartitions, diskID)
+	// Si el espacio empieza en la cabecera (vacía), se reutiliza el primer EBR conservando el enlace
+	if space.Start == extended.Start {
+		newEBR.PartNext = chain[0].EBR.PartNext
+		if err := Utilities.WriteObject(file, newEBR, space.Start); err != nil {
+			return err
+		}
+		fmt.Println("Nuevo EBR creado:")
+		Structs.PrintEBR(newEBR)
+		return nil
 	}
 
-	fmt.Println("Partición desmontada con éxito.")
-	PrintMountedPartitions() // Mostrar las particiones montadas restantes
-}
+	// Enlazar el nuevo EBR después del último EBR que está antes de él en el disco
+	// (la cadena se mantiene ordenada por posición)
+	prev := 0
+	for i, logical := range chain {
+		if logical.Position < space.Start {
+			prev = i
+		}
+	}
+	newEBR.PartNext = chain[prev].EBR.PartNext
+	chain[prev].EBR.PartNext = space.Start
 
-// Función para obtener el ID del último disco montado
-func getLastDiskID() string {
-	var lastDiskID string
-	for diskID := range mountedPartitions {
-		lastDiskID = diskID
+	if err := Utilities.WriteObject(file, newEBR, space.Start); err != nil {
+		return err
 	}
-	return lastDiskID
+	if err := Utilities.WriteObject(file, chain[prev].EBR, chain[prev].Position); err != nil {
+		return err
+	}
+
+	fmt.Println("Nuevo EBR creado:")
+	Structs.PrintEBR(newEBR)
+	return nil
 }
 
-func generateDiskID(path string) string {
-	return strings.ToLower(path)
+// Función para eliminar una partición lógica manteniendo enlazada la cadena de EBRs
+func DeleteLogicalPartition(file *os.File, extended Structs.Partition, chain []LogicalPartition, index int, delete_ string) error {
+	target := chain[index]
+
+	// Eliminar completamente: sobrescribir los datos de la lógica con '\0'
+	if delete_ == "full" {
+		Utilities.FillWithZeros(file, target.EBR.PartStart, target.EBR.PartSize)
+		Utilities.VerifyZeros(file, target.EBR.PartStart, target.EBR.PartSize)
+	}
+
+	// El primer EBR nunca se elimina: queda vacío pero conserva el puntero al siguiente
+	if index == 0 {
+		emptyEBR := Structs.EBR{
+			PartFit:   target.EBR.PartFit,
+			PartStart: extended.Start,
+			PartSize:  0,
+			PartNext:  target.EBR.PartNext,
+		}
+		return Utilities.WriteObject(file, emptyEBR, target.Position)
+	}
+
+	// El EBR anterior pasa a apuntar al siguiente del eliminado
+	prev := chain[index-1]
+	prev.EBR.PartNext = target.EBR.PartNext
+	if err := Utilities.WriteObject(file, prev.EBR, prev.Position); err != nil {
+		return err
+	}
+
+	return Utilities.WriteObject(file, Structs.EBR{}, target.Position)
+}
+
+// Función para eliminar particiones
+func DeletePartition(path string, name string, delete_ string) {
+	fmt.Println("======Start DELETE PARTITION======")
+	fmt.Println("Path:", path)
+	fmt.Println("Name:", name)
+	fmt.Println("Delete type:", delete_)
+
+	// Abrir el archivo binario en la ruta proporcionada
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		fmt.Println("Error: Could not open file at path:", path)
+		return
+	}
+	defer file.Close()
+
+	// Leer la tabla de particiones (MBR o GPT)
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	// Buscar la partición por nombre (primaria, extendida o lógica)
+	partition, err := resolvePartition(file, table, name, false)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if partition.Type == 'l' {
+		extended, _ := table.Extended()
+		chain, err := ReadEBRChain(file, extended)
+		if err != nil {
+			fmt.Println("Error al leer EBR:", err)
+			return
+		}
+		for j, logical := range chain {
+			if logical.Position != partition.EBRPosition {
+				continue
+			}
+			if err := DeleteLogicalPartition(file, extended, chain, j, delete_); err != nil {
+				fmt.Println("Error al eliminar la partición lógica:", err)
+				return
+			}
+			if delete_ == "full" {
+				fmt.Println("Partición lógica eliminada en modo Full.")
+			} else {
+				fmt.Println("Partición lógica eliminada en modo Fast.")
+			}
+			break
+		}
+	} else {
+		// Si es una partición extendida, sus particiones lógicas desaparecen con ella.
+		// En modo Fast solo se limpia la entrada de la tabla (los EBRs quedan en el disco);
+		// en modo Full se sobrescribe toda la extendida, incluidos los EBRs.
+		if partition.Type == 'e' {
+			fmt.Println("Eliminando particiones lógicas dentro de la partición extendida...")
+			extended, _ := table.Extended()
+			PrintEBRChain(file, extended)
+		}
+
+		// Proceder a eliminar la partición (extendida o primaria)
+		if delete_ == "fast" {
+			// Eliminar rápido: Resetear manualmente los campos de la partición
+			table.Remove(partition.Index)
+			fmt.Println("Partición eliminada en modo Fast.")
+		} else if delete_ == "full" {
+			// Eliminar completamente: Resetear manualmente y sobrescribir con '\0'
+			table.Remove(partition.Index)
+			// Escribir '\0' en el espacio de la partición en el disco
+			Utilities.FillWithZeros(file, partition.Start, partition.Size)
+			fmt.Println("Partición eliminada en modo Full.")
+
+			// Leer y verificar si el área está llena de ceros
+			Utilities.VerifyZeros(file, partition.Start, partition.Size)
+		}
+	}
+
+	// Sobrescribir la tabla de particiones
+	if err := table.Write(file); err != nil {
+		fmt.Println("Error: Could not write partition table to file")
+		return
+	}
+
+	// Mostrar la tabla actualizada
+	fmt.Println("Tabla de particiones actualizada después de la eliminación:")
+	table.Print()
+
+	// Si queda una partición extendida, mostrar los EBRs actualizados
+	if extended, ok := table.Extended(); ok {
+		fmt.Println("Imprimiendo EBRs actualizados en la partición extendida:")
+		PrintEBRChain(file, extended)
+	}
+
+	fmt.Println("======FIN DELETE PARTITION======")
+}
+
+func ModifyPartition(path string, name string, add int, unit string) error {
+	fmt.Println("======Start MODIFY PARTITION======")
+	// Abrir el archivo binario en la ruta proporcionada
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		fmt.Println("Error: Could not open file at path:", path)
+		return err
+	}
+	defer file.Close()
+
+	// Leer la tabla de particiones (MBR o GPT)
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+
+	// Imprimir la tabla antes de modificar
+	fmt.Println("Tabla de particiones antes de la modificación:")
+	table.Print()
+
+	// Buscar la partición por nombre (primaria, extendida o lógica)
+	partition, err := resolvePartition(file, table, name, false)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+
+	// Convertir unidades a bytes (validando que no se desborde)
+	if unit != "b" && unit != "k" && unit != "m" {
+		fmt.Println("Error: Unidad desconocida, debe ser 'b', 'k' o 'm'")
+		return fmt.Errorf("unidad desconocida, debe ser 'b', 'k' o 'm'")
+	}
+	addBytes, err := Utilities.ConvertToBytes(add, unit)
+	if err == nil && addBytes > 0 && partition.Size > math.MaxInt64-addBytes {
+		err = fmt.Errorf("el nuevo tamaño de la partición excede el máximo representable")
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+
+	newSize := partition.Size + addBytes
+
+	// Comprobar si es posible agregar o quitar espacio
+	if addBytes > 0 {
+		// Agregar espacio: el nuevo final no puede alcanzar al siguiente vecino físico
+		limit, err := nextNeighbourStart(file, table, partition)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return err
+		}
+		if partition.Start+newSize > limit {
+			fmt.Printf("Error: No hay suficiente espacio libre después de la partición (disponible: %d bytes)\n", limit-partition.Start-partition.Size)
+			return fmt.Errorf("no hay suficiente espacio libre después de la partición (disponible: %d bytes)", limit-partition.Start-partition.Size)
+		}
+	} else {
+		// Quitar espacio: verificar el tamaño mínimo que necesita el contenido de la partición
+		minimum, err := minimumPartitionSize(file, table, partition)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return err
+		}
+		if newSize < minimum {
+			fmt.Printf("Error: La partición no puede reducirse por debajo de %d bytes\n", minimum)
+			return fmt.Errorf("la partición no puede reducirse por debajo de %d bytes", minimum)
+		}
+	}
+
+	// Guardar el nuevo tamaño en el EBR (lógicas) o en la tabla de particiones (primarias y extendidas)
+	if partition.Type == 'l' {
+		var ebr Structs.EBR
+		if err := Utilities.ReadObject(file, &ebr, partition.EBRPosition); err != nil {
+			fmt.Println("Error al leer EBR:", err)
+			return err
+		}
+
+		// Actualizar el tamaño en el EBR y escribirlo de nuevo (los enlaces no cambian)
+		ebr.PartSize = newSize
+		if err := Utilities.WriteObject(file, ebr, partition.EBRPosition); err != nil {
+			fmt.Println("Error al escribir el EBR actualizado:", err)
+			return err
+		}
+
+		// Imprimir el EBR modificado
+		fmt.Println("EBR modificado:")
+		Structs.PrintEBR(ebr)
+	} else {
+		partition.Size = newSize
+		table.Update(partition)
+
+		// Sobrescribir la tabla actualizada
+		if err := table.Write(file); err != nil {
+			fmt.Println("Error al escribir la tabla de particiones actualizada:", err)
+			return err
+		}
+
+		// Si es la extendida, validar que su cadena de EBRs siga dentro de ella
+		if extended, ok := table.Extended(); ok && partition.Type == 'e' {
+			fmt.Println("EBRs de la partición extendida:")
+			PrintEBRChain(file, extended)
+		}
+	}
+
+	// Imprimir la tabla modificada
+	fmt.Println("Tabla de particiones después de la modificación:")
+	table.Print()
+
+	// El sistema de archivos no crece solo con la partición
+	if _, formatted := FilesystemFootprint(file, partition.Start); formatted && addBytes > 0 {
+		fmt.Println("La partición tiene un sistema de archivos; use resizefs para aprovechar el nuevo espacio.")
+	}
+
+	fmt.Println("======END MODIFY PARTITION======")
+	return nil
+}
+
+// Función para obtener el inicio del siguiente vecino físico de una partición (el límite hasta donde puede crecer)
+func nextNeighbourStart(file *os.File, table PartitionTable, partition PartitionLocation) (int64, error) {
+	// Una lógica está limitada por el siguiente EBR en el disco o por el final de la extendida
+	if partition.Type == 'l' {
+		extended, _ := table.Extended()
+		limit := extended.Start + extended.Size
+
+		chain, err := ReadEBRChain(file, extended)
+		if err != nil {
+			return 0, fmt.Errorf("no se pudo leer la cadena de EBRs: %v", err)
+		}
+		for _, logical := range chain {
+			if logical.EBR.PartSize > 0 && logical.Position > partition.EBRPosition && logical.Position < limit {
+				limit = logical.Position
+			}
+		}
+		return limit, nil
+	}
+
+	// Una primaria o extendida está limitada por la siguiente partición en el disco o por el final del área disponible
+	limit := table.LastUsable()
+	for _, other := range table.Entries() {
+		if other.Index == partition.Index {
+			continue
+		}
+		if other.Start > partition.Start && other.Start < limit {
+			limit = other.Start
+		}
+	}
+	return limit, nil
+}
+
+// Función para obtener el tamaño mínimo de una partición según su contenido:
+// el espacio que ocupa su sistema de archivos o, si es extendida, el de sus particiones lógicas
+func minimumPartitionSize(file *os.File, table PartitionTable, partition PartitionLocation) (int64, error) {
+	var minimum int64 = 1
+
+	if partition.Type == 'e' {
+		// La extendida debe contener al menos su primer EBR y todas sus lógicas
+		minimum = int64(binary.Size(Structs.EBR{}))
+		extended, _ := table.Extended()
+		chain, err := ReadEBRChain(file, extended)
+		if err != nil {
+			return 0, fmt.Errorf("no se pudo leer la cadena de EBRs: %v", err)
+		}
+		for _, logical := range chain {
+			if logical.EBR.PartSize > 0 {
+				if end := logical.EBR.PartStart + logical.EBR.PartSize - partition.Start; end > minimum {
+					minimum = end
+				}
+			}
+		}
+		return minimum, nil
+	}
+
+	// Si la partición está formateada, no se puede cortar su sistema de archivos
+	if footprint, formatted := FilesystemFootprint(file, partition.Start); formatted && footprint > minimum {
+		minimum = footprint
+	}
+	return minimum, nil
+}
+
+// Función para obtener el espacio que ocupa el sistema de archivos (EXT2/EXT3) de una partición,
+// desde su inicio hasta el final del área de bloques. Devuelve false si la partición no está formateada.
+func FilesystemFootprint(file *os.File, partitionStart int64) (int64, bool) {
+	var superblock Structs.Superblock
+	if err := Utilities.ReadObject(file, &superblock, partitionStart); err != nil {
+		return 0, false
+	}
+	if superblock.S_magic != 0xEF53 {
+		return 0, false
+	}
+	return superblock.S_block_start + int64(superblock.S_blocks_count)*int64(superblock.S_block_size) - partitionStart, true
+}
+
+// Función para actualizar las posiciones absolutas del Superblock de una partición que se movió delta bytes.
+// Los punteros internos del sistema de archivos son índices, por lo que solo cambian los inicios de cada área.
+func RebaseSuperblock(file *os.File, partitionStart int64, delta int64) error {
+	var superblock Structs.Superblock
+	if err := Utilities.ReadObject(file, &superblock, partitionStart); err != nil {
+		return err
+	}
+	if superblock.S_magic != 0xEF53 || delta == 0 {
+		return nil // La partición no tiene sistema de archivos o no se movió
+	}
+
+	shiftSuperblock(&superblock, delta)
+	return Utilities.WriteObject(file, superblock, partitionStart)
+}
+
+// Función para desplazar delta bytes los inicios de las áreas de un Superblock
+func shiftSuperblock(superblock *Structs.Superblock, delta int64) {
+	superblock.S_bm_inode_start += delta
+	superblock.S_bm_block_start += delta
+	superblock.S_inode_start += delta
+	superblock.S_block_start += delta
+}
+
+// Función para compactar un disco: mueve las particiones hacia el inicio del disco (y las lógicas
+// hacia el inicio de la extendida) para juntar todo el espacio libre al final
+func CompactDisk(path string) error {
+	fmt.Println("======Start COMPACT======")
+	fmt.Println("Path:", path)
+
+	// No se puede compactar un disco con particiones montadas: sus particiones cambian de posición
+	diskID, _ := generateDiskID(path)
+	if partitions := mountedPartitions[diskID]; len(partitions) > 0 {
+		var ids []string
+		for _, partition := range partitions {
+			ids = append(ids, partition.ID)
+		}
+		fmt.Println("Error: El disco tiene particiones montadas:", strings.Join(ids, ", "))
+		return fmt.Errorf("el disco tiene particiones montadas (%s), desmóntelas antes de compactarlo", strings.Join(ids, ", "))
+	}
+
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		fmt.Println("Error: Could not open file at path:", path)
+		return err
+	}
+	defer file.Close()
+
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+
+	// Primero se compactan las lógicas dentro de la extendida
+	if extended, ok := table.Extended(); ok {
+		if err := compactLogicalPartitions(file, extended); err != nil {
+			fmt.Println("Error al compactar las particiones lógicas:", err)
+			return err
+		}
+	}
+
+	// Ordenar las particiones por su posición física
+	entries := table.Entries()
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].Start < entries[b].Start
+	})
+
+	// Mover cada partición justo después de la anterior
+	cursor := table.FirstUsable()
+	for _, partition := range entries {
+		if partition.Start > cursor {
+			delta := cursor - partition.Start
+			fmt.Printf("Moviendo la partición %s de %d a %d\n", partition.Name, partition.Start, cursor)
+
+			if err := Utilities.MoveBytes(file, partition.Start, cursor, partition.Size); err != nil {
+				return err
+			}
+			partition.Start = cursor
+			table.Update(partition)
+
+			// Actualizar las posiciones absolutas guardadas dentro de la partición
+			if partition.Type == 'e' {
+				extended, _ := table.Extended()
+				if err := rebaseEBRChain(file, extended, delta); err != nil {
+					return err
+				}
+			} else if err := RebaseSuperblock(file, cursor, delta); err != nil {
+				return err
+			}
+		}
+		cursor = partition.Start + partition.Size
+	}
+
+	if err := table.Write(file); err != nil {
+		fmt.Println("Error: Could not write partition table to file")
+		return err
+	}
+
+	fmt.Println("Tabla de particiones después de compactar:")
+	table.Print()
+	if extended, ok := table.Extended(); ok {
+		PrintEBRChain(file, extended)
+	}
+	fmt.Printf("Espacio libre contiguo al final del disco: %d bytes\n", table.LastUsable()-cursor)
+
+	fmt.Println("======End COMPACT======")
+	return nil
+}
+
+// Función para compactar las particiones lógicas hacia el inicio de la partición extendida,
+// reescribiendo los punteros PartStart/PartNext de los EBRs
+func compactLogicalPartitions(file *os.File, extended Structs.Partition) error {
+	chain, err := ReadEBRChain(file, extended)
+	if err != nil {
+		return fmt.Errorf("no se pudo leer la cadena de EBRs: %v", err)
+	}
+
+	var logicals []LogicalPartition
+	for _, logical := range chain {
+		if logical.EBR.PartSize > 0 {
+			logicals = append(logicals, logical)
+		}
+	}
+	sort.Slice(logicals, func(a, b int) bool {
+		return logicals[a].Position < logicals[b].Position
+	})
+
+	// Sin lógicas solo queda el EBR cabecera vacío
+	if len(logicals) == 0 {
+		head := chain[0].EBR
+		head.PartNext = -1
+		return Utilities.WriteObject(file, head, extended.Start)
+	}
+
+	ebrSize := int64(binary.Size(Structs.EBR{}))
+	cursor := extended.Start
+	var newPositions []int64
+	for i := range logicals {
+		newPositions = append(newPositions, cursor)
+
+		// Mover los datos de la lógica justo después de su nuevo EBR
+		oldStart := logicals[i].EBR.PartStart
+		newStart := cursor + ebrSize
+		if err := Utilities.MoveBytes(file, oldStart, newStart, logicals[i].EBR.PartSize); err != nil {
+			return err
+		}
+		if err := RebaseSuperblock(file, newStart, newStart-oldStart); err != nil {
+			return err
+		}
+		logicals[i].EBR.PartStart = newStart
+		cursor = newStart + logicals[i].EBR.PartSize
+	}
+
+	// Reescribir la cadena de EBRs en sus nuevas posiciones
+	for i := range logicals {
+		logicals[i].EBR.PartNext = -1
+		if i+1 < len(logicals) {
+			logicals[i].EBR.PartNext = newPositions[i+1]
+		}
+		if err := Utilities.WriteObject(file, logicals[i].EBR, newPositions[i]); err != nil {
+			return err
+		}
+	}
+
+	// Borrar los EBRs antiguos que quedaron en el espacio libre
+	for _, logical := range chain {
+		if logical.Position >= cursor {
+			if err := Utilities.WriteObject(file, Structs.EBR{}, logical.Position); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Función para desplazar delta bytes todas las posiciones de la cadena de EBRs de una extendida que se movió
+func rebaseEBRChain(file *os.File, extended Structs.Partition, delta int64) error {
+	visited := make(map[int64]bool)
+
+	ebrPos := extended.Start
+	for ebrPos != -1 {
+		if visited[ebrPos] {
+			return fmt.Errorf("la cadena de EBRs tiene un ciclo en la posición %d", ebrPos)
+		}
+		visited[ebrPos] = true
+
+		var ebr Structs.EBR
+		if err := Utilities.ReadObject(file, &ebr, ebrPos); err != nil {
+			return err
+		}
+
+		// El EBR cabecera vacío apunta al inicio de la extendida
+		ebr.PartStart += delta
+		if ebr.PartNext != -1 {
+			ebr.PartNext += delta
+		}
+		if err := Utilities.WriteObject(file, ebr, ebrPos); err != nil {
+			return err
+		}
+
+		if ebr.PartSize > 0 {
+			if err := RebaseSuperblock(file, ebr.PartStart, delta); err != nil {
+				return err
+			}
+		}
+		ebrPos = ebr.PartNext
+	}
+	return nil
+}
+
+// Problema de consistencia encontrado al revisar un disco (fdisk -check)
+type DiskIssue struct {
+	Problem  string `json:"problem"`
+	Repaired bool   `json:"repaired"`
+}
+
+// Función para revisar la tabla de particiones y la cadena de EBRs de un disco
+// Reporta particiones fuera del disco, traslapes, ciclos y punteros colgantes en los EBRs,
+// nombres duplicados y marcas de montaje sin montaje; con repair corrige solo lo que no es ambiguo
+func CheckDisk(path string, repair bool) ([]DiskIssue, error) {
+	fmt.Println("======Start CHECK======")
+	fmt.Println("Path:", path)
+	fmt.Println("Repair:", repair)
+
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		fmt.Println("Error: Could not open file at path:", path)
+		return nil, err
+	}
+	defer file.Close()
+
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return nil, err
+	}
+
+	var issues []DiskIssue
+	tableChanged := false
+
+	// Particiones fuera de los límites del disco; si solo se pasan del final y su
+	// contenido cabe, se recortan hasta el final del disco
+	entries := table.Entries()
+	for _, entry := range entries {
+		if entry.Size < 0 || entry.Start < table.FirstUsable() || entry.Start >= table.LastUsable() {
+			issues = append(issues, DiskIssue{Problem: fmt.Sprintf("la partición %s está fuera del disco (inicio %d, tamaño %d)", entry.Name, entry.Start, entry.Size)})
+			continue
+		}
+		if end := entry.Start + entry.Size; end > table.LastUsable() {
+			issue := DiskIssue{Problem: fmt.Sprintf("la partición %s termina en %d, después del final del disco (%d)", entry.Name, end, table.LastUsable())}
+			if minimum, err := minimumPartitionSize(file, table, entry); repair && err == nil && table.LastUsable()-entry.Start >= minimum {
+				entry.Size = table.LastUsable() - entry.Start
+				table.Update(entry)
+				tableChanged = true
+				issue.Repaired = true
+			}
+			issues = append(issues, issue)
+		}
+	}
+
+	// Particiones que se traslapan entre sí
+	entries = table.Entries()
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].Start < entries[b].Start
+	})
+	for i := 1; i < len(entries); i++ {
+		for j := 0; j < i; j++ {
+			if entries[j].Start+entries[j].Size > entries[i].Start {
+				issues = append(issues, DiskIssue{Problem: fmt.Sprintf("las particiones %s y %s se traslapan", entries[j].Name, entries[i].Name)})
+			}
+		}
+	}
+
+	extendedCount := 0
+	for _, entry := range entries {
+		if entry.Type == 'e' {
+			extendedCount++
+		}
+	}
+	if extendedCount > 1 {
+		issues = append(issues, DiskIssue{Problem: fmt.Sprintf("el disco tiene %d particiones extendidas", extendedCount)})
+	}
+
+	if tableChanged {
+		if err := table.Write(file); err != nil {
+			fmt.Println("Error: Could not write partition table to file")
+			return issues, err
+		}
+	}
+
+	// Cadena de EBRs de la partición extendida
+	if extended, ok := table.Extended(); ok {
+		chainIssues, err := checkEBRChain(file, extended, repair)
+		issues = append(issues, chainIssues...)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return issues, err
+		}
+	}
+
+	// Recorrer todas las particiones (incluidas las lógicas) para los nombres y los montajes
+	var all []PartitionLocation
+	findPartition(file, table, func(location PartitionLocation) bool {
+		all = append(all, location)
+		return false
+	})
+
+	names := make(map[string]int)
+	for _, location := range all {
+		if key := normalizePartitionKey(location.Name); key != "" {
+			names[key]++
+		}
+	}
+	for name, count := range names {
+		if count > 1 {
+			issues = append(issues, DiskIssue{Problem: fmt.Sprintf("hay %d particiones con el nombre %s", count, name)})
+		}
+	}
+
+	// Una partición marcada como montada que no está en la tabla de montajes quedó de una sesión anterior
+	for _, location := range all {
+		if location.Status != '1' || isMountedID(location.Id) {
+			continue
+		}
+		issue := DiskIssue{Problem: fmt.Sprintf("la partición %s está marcada como montada (%s) pero no está montada", location.Name, location.Id)}
+		if repair {
+			if err := setPartitionMount(file, table, location, '0', ""); err != nil {
+				return issues, err
+			}
+			issue.Repaired = true
+		}
+		issues = append(issues, issue)
+	}
+
+	if len(issues) == 0 {
+		fmt.Println("No se encontraron problemas en el disco")
+	}
+	for _, issue := range issues {
+		if issue.Repaired {
+			fmt.Println("Reparado:", issue.Problem)
+		} else {
+			fmt.Println("Problema:", issue.Problem)
+		}
+	}
+
+	fmt.Println("======End CHECK======")
+	return issues, nil
+}
+
+// Función para crear un disco de 1MB con la partición primaria "a" marcada en el disco como montada con el ID id,
+// pero sin estar en la tabla de montajes, como queda al reiniciar sin desmontar. Es el caso que repara fdisk -check -repair
+// y se usa en sus pruebas
+func CreateStaleMountDisk(path string, id string) error {
+	Mkdisk(1024, "ff", "k", path, "mbr", "sparse")
+	if err := Fdisk(100, path, "a", "k", "p", ""); err != nil {
+		return err
+	}
+
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		return err
+	}
+	location, err := FindPartitionByName(file, "a")
+	if err != nil {
+		return err
+	}
+	return setPartitionMount(file, table, location, '1', id)
+}
+
+// Función para revisar la cadena de EBRs de una extendida: ciclos, punteros fuera de la extendida,
+// lógicas que se salen de la extendida, traslapes y el orden de la cadena
+func checkEBRChain(file *os.File, extended Structs.Partition, repair bool) ([]DiskIssue, error) {
+	var issues []DiskIssue
+	ebrSize := int64(binary.Size(Structs.EBR{}))
+	extendedEnd := extended.Start + extended.Size
+
+	// Recorrer la cadena; un ciclo o un puntero fuera de la extendida se corta en el EBR que lo tiene
+	var chain []LogicalPartition
+	visited := make(map[int64]bool)
+	ebrPos := extended.Start
+	for ebrPos != -1 {
+		var ebr Structs.EBR
+		if err := Utilities.ReadObject(file, &ebr, ebrPos); err != nil {
+			return issues, fmt.Errorf("no se pudo leer el EBR en la posición %d: %v", ebrPos, err)
+		}
+		visited[ebrPos] = true
+		chain = append(chain, LogicalPartition{Position: ebrPos, EBR: ebr})
+
+		next := ebr.PartNext
+		problem := ""
+		if next != -1 && visited[next] {
+			problem = fmt.Sprintf("la cadena de EBRs tiene un ciclo: el EBR en %d apunta al EBR en %d", ebrPos, next)
+		} else if next != -1 && (next < extended.Start || next+ebrSize > extendedEnd) {
+			problem = fmt.Sprintf("el EBR en %d apunta fuera de la partición extendida (%d)", ebrPos, next)
+		}
+		if problem != "" {
+			issue := DiskIssue{Problem: problem}
+			if repair {
+				ebr.PartNext = -1
+				chain[len(chain)-1].EBR = ebr
+				if err := Utilities.WriteObject(file, ebr, ebrPos); err != nil {
+					return issues, err
+				}
+				issue.Repaired = true
+			}
+			issues = append(issues, issue)
+			break
+		}
+		ebrPos = next
+	}
+
+	// Lógicas que se salen de la extendida o cuyos datos no están después de su EBR
+	for i := range chain {
+		logical := &chain[i]
+		ebr := &logical.EBR
+		name := strings.TrimRight(string(ebr.PartName[:]), "\x00")
+		if ebr.PartSize == 0 {
+			continue
+		}
+		if ebr.PartSize < 0 || ebr.PartStart != logical.Position+ebrSize {
+			issues = append(issues, DiskIssue{Problem: fmt.Sprintf("el EBR de la lógica %s en %d no apunta a sus datos (inicio %d, tamaño %d)", name, logical.Position, ebr.PartStart, ebr.PartSize)})
+			continue
+		}
+		if end := ebr.PartStart + ebr.PartSize; end > extendedEnd {
+			issue := DiskIssue{Problem: fmt.Sprintf("la lógica %s termina en %d, después del final de la extendida (%d)", name, end, extendedEnd)}
+			footprint, _ := FilesystemFootprint(file, ebr.PartStart)
+			if repair && extendedEnd-ebr.PartStart >= max(footprint, 1) {
+				ebr.PartSize = extendedEnd - ebr.PartStart
+				if err := Utilities.WriteObject(file, *ebr, logical.Position); err != nil {
+					return issues, err
+				}
+				issue.Repaired = true
+			}
+			issues = append(issues, issue)
+		}
+	}
+
+	// Lógicas que se traslapan (cada una ocupa su EBR más sus datos)
+	sorted := append([]LogicalPartition(nil), chain...)
+	sort.Slice(sorted, func(a, b int) bool {
+		return sorted[a].Position < sorted[b].Position
+	})
+	for i := 1; i < len(sorted); i++ {
+		previous := sorted[i-1]
+		previousEnd := previous.Position + ebrSize
+		if previous.EBR.PartSize > 0 {
+			previousEnd = max(previousEnd, previous.EBR.PartStart+previous.EBR.PartSize)
+		}
+		if previousEnd > sorted[i].Position {
+			issues = append(issues, DiskIssue{Problem: fmt.Sprintf("los EBRs en %d y %d se traslapan", previous.Position, sorted[i].Position)})
+		}
+	}
+
+	// La cadena debe seguir el orden físico de los EBRs; se vuelve a enlazar en orden
+	ordered := sort.SliceIsSorted(chain, func(a, b int) bool {
+		return chain[a].Position < chain[b].Position
+	})
+	if !ordered {
+		issue := DiskIssue{Problem: "la cadena de EBRs no sigue el orden de las posiciones en el disco"}
+		if repair {
+			for i := range sorted {
+				sorted[i].EBR.PartNext = -1
+				if i+1 < len(sorted) {
+					sorted[i].EBR.PartNext = sorted[i+1].Position
+				}
+				if err := Utilities.WriteObject(file, sorted[i].EBR, sorted[i].Position); err != nil {
+					return issues, err
+				}
+			}
+			issue.Repaired = true
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues, nil
+}
+
+// Partición propuesta por recover a partir de lo que se encontró en la imagen del disco
+type RecoveredPartition struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"` // "p" si se encontró un sistema de archivos, "e" si se encontró una cadena de EBRs
+	Start    int64  `json:"start"`
+	Size     int64  `json:"size"`
+	Source   string `json:"source"` // "ext2", "ext3" o "ebr"
+	Logicals int    `json:"logicals,omitempty"`
+	fit      byte
+}
+
+// Desplazamiento del campo S_magic dentro del Superblock (cinco int32 y las dos fechas de 17 bytes antes de S_mnt_count)
+const superblockMagicOffset = 5*4 + 17 + 17 + 4
+
+// Función para recuperar particiones perdidas (por ejemplo, tras un fdisk -delete=fast). Se analiza la imagen
+// del disco buscando Superblocks (magic 0xEF53) y EBRs válidos en el espacio que la tabla tiene libre, y se
+// proponen las entradas reconstruidas. Solo con confirm se escriben en la tabla de particiones.
+func RecoverPartitions(path string, confirm bool) ([]RecoveredPartition, error) {
+	fmt.Println("======Start RECOVER======")
+	fmt.Println("Path:", path)
+
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		fmt.Println("Error: Could not open file at path:", path)
+		return nil, err
+	}
+	defer file.Close()
+
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return nil, err
+	}
+
+	superblocks, ebrs, err := scanDiskImage(file, table)
+	if err != nil {
+		fmt.Println("Error al analizar el disco:", err)
+		return nil, err
+	}
+	fmt.Printf("Análisis terminado: %d Superblocks y %d EBRs encontrados\n", len(superblocks), len(ebrs))
+
+	// Las zonas que ya tienen una partición en la tabla no se tocan
+	var used []FreeSpace
+	for _, entry := range table.Entries() {
+		used = append(used, FreeSpace{Start: entry.Start, Size: entry.Size})
+	}
+	overlapsUsed := func(start int64, size int64) bool {
+		for _, area := range used {
+			if start < area.Start+area.Size && area.Start < start+size {
+				return true
+			}
+		}
+		return false
+	}
+
+	proposals := []RecoveredPartition{}
+
+	// Las cadenas de EBRs fuera de la tabla son particiones extendidas borradas (solo en MBR, con una extendida como máximo)
+	if _, hasExtended := table.Extended(); !hasExtended && table.Kind() == "mbr" {
+		for _, chain := range recoverEBRChains(ebrs) {
+			if !overlapsUsed(chain.Start, chain.Size) {
+				proposals = append(proposals, chain)
+				used = append(used, FreeSpace{Start: chain.Start, Size: chain.Size})
+				break
+			}
+		}
+	}
+
+	// Los Superblocks que quedan fuera de las particiones (y de las extendidas recuperadas) son primarias borradas
+	for _, position := range superblocks {
+		var superblock Structs.Superblock
+		if err := Utilities.ReadObject(file, &superblock, position); err != nil {
+			continue
+		}
+		size := superblock.S_block_start + int64(superblock.S_blocks_count)*int64(superblock.S_block_size) - position
+		if overlapsUsed(position, size) {
+			continue
+		}
+		proposals = append(proposals, RecoveredPartition{
+			Type:   "p",
+			Start:  position,
+			Size:   size,
+			Source: fmt.Sprintf("ext%d", superblock.S_filesystem_type),
+			fit:    table.DiskFit(),
+		})
+		used = append(used, FreeSpace{Start: position, Size: size})
+	}
+
+	sort.Slice(proposals, func(a, b int) bool {
+		return proposals[a].Start < proposals[b].Start
+	})
+
+	// La tabla solo tiene espacio para Capacity() entradas
+	free := table.Capacity() - len(table.Entries())
+	if len(proposals) > free {
+		fmt.Printf("Advertencia: La tabla solo tiene %d entradas libres, se descartan %d particiones encontradas\n", free, len(proposals)-free)
+		proposals = proposals[:free]
+	}
+
+	// Nombres nuevos que no se repitan en el disco (el nombre original de una primaria no se guarda en sus datos)
+	next := 1
+	for i := range proposals {
+		for {
+			name := fmt.Sprintf("rec%d", next)
+			next++
+			if _, err := resolvePartition(file, table, name, false); err != nil {
+				proposals[i].Name = name
+				break
+			}
+		}
+	}
+
+	if len(proposals) == 0 {
+		fmt.Println("No se encontraron particiones para recuperar")
+		fmt.Println("======End RECOVER======")
+		return proposals, nil
+	}
+
+	fmt.Println("Particiones encontradas:")
+	for _, proposal := range proposals {
+		detail := proposal.Source
+		if proposal.Type == "e" {
+			detail = fmt.Sprintf("%d particiones lógicas", proposal.Logicals)
+		}
+		fmt.Printf(" - %s: tipo %s, inicio %d, tamaño %d (%s)\n", proposal.Name, proposal.Type, proposal.Start, proposal.Size, detail)
+	}
+
+	if !confirm {
+		fmt.Println("No se modificó el disco, use -confirm para escribir estas particiones en la tabla")
+		fmt.Println("======End RECOVER======")
+		return proposals, nil
+	}
+
+	for _, proposal := range proposals {
+		if _, err := table.Add(proposal.Name, proposal.Type[0], proposal.fit, proposal.Start, proposal.Size); err != nil {
+			fmt.Println("Error:", err)
+			return nil, err
+		}
+	}
+	if err := table.Write(file); err != nil {
+		fmt.Println("Error: Could not write partition table to file")
+		return nil, err
+	}
+
+	fmt.Printf("Se recuperaron %d particiones\n", len(proposals))
+	table.Print()
+	fmt.Println("======End RECOVER======")
+	return proposals, nil
+}
+
+// Función para recorrer la imagen del disco en bloques buscando Superblocks y EBRs con datos coherentes.
+// Devuelve las posiciones de los Superblocks y los EBRs encontrados por posición.
+func scanDiskImage(file *os.File, table PartitionTable) ([]int64, map[int64]Structs.EBR, error) {
+	const chunkSize = 1 << 20
+	superblockSize := int64(binary.Size(Structs.Superblock{}))
+	ebrSize := int64(binary.Size(Structs.EBR{}))
+	diskSize := table.DiskSize()
+	magic := []byte{0x53, 0xEF, 0x00, 0x00}
+
+	var superblocks []int64
+	ebrs := make(map[int64]Structs.EBR)
+	buffer := make([]byte, chunkSize+ebrSize)
+
+	for offset := table.FirstUsable(); offset < diskSize; offset += chunkSize {
+		n, err := file.ReadAt(buffer, offset)
+		if err != nil && err != io.EOF {
+			return nil, nil, err
+		}
+		data := buffer[:n]
+
+		// Superblocks: buscar el magic y validar el Superblock completo
+		for i := 0; i < n && i < chunkSize; {
+			index := bytes.Index(data[i:], magic)
+			if index < 0 || i+index >= chunkSize {
+				break
+			}
+			position := offset + int64(i+index) - superblockMagicOffset
+			if position >= table.FirstUsable() && plausibleSuperblock(file, position, superblockSize, diskSize) {
+				superblocks = append(superblocks, position)
+			}
+			i += index + 1
+		}
+
+		// EBRs: el inicio de los datos va justo después del EBR (o en el mismo EBR si es una cabecera vacía)
+		if table.Kind() != "mbr" {
+			continue
+		}
+		for i := 0; i < chunkSize && int64(i)+ebrSize <= int64(n); i++ {
+			position := offset + int64(i)
+			start := int64(binary.LittleEndian.Uint64(data[i+2:]))
+			if start != position+ebrSize && start != position {
+				continue
+			}
+			var ebr Structs.EBR
+			if err := binary.Read(bytes.NewReader(data[i:int64(i)+ebrSize]), binary.LittleEndian, &ebr); err != nil {
+				continue
+			}
+			if plausibleEBR(ebr, position, ebrSize, diskSize) {
+				ebrs[position] = ebr
+			}
+		}
+	}
+
+	return superblocks, ebrs, nil
+}
+
+// Función para validar un Superblock encontrado por su magic: las áreas deben ir en orden después del
+// Superblock, con los tamaños de inodo y bloque del sistema y sin salirse del disco
+func plausibleSuperblock(file *os.File, position int64, superblockSize int64, diskSize int64) bool {
+	var superblock Structs.Superblock
+	if err := Utilities.ReadObject(file, &superblock, position); err != nil {
+		return false
+	}
+	if superblock.S_magic != 0xEF53 || (superblock.S_filesystem_type != 2 && superblock.S_filesystem_type != 3) {
+		return false
+	}
+	if superblock.S_inodes_count <= 0 || superblock.S_blocks_count <= 0 ||
+		superblock.S_inode_size != int32(binary.Size(Structs.Inode{})) || superblock.S_block_size != int32(binary.Size(Structs.Fileblock{})) {
+		return false
+	}
+	if superblock.S_bm_inode_start < position+superblockSize || superblock.S_bm_block_start <= superblock.S_bm_inode_start ||
+		superblock.S_inode_start <= superblock.S_bm_block_start || superblock.S_block_start <= superblock.S_inode_start {
+		return false
+	}
+	return superblock.S_block_start+int64(superblock.S_blocks_count)*int64(superblock.S_block_size) <= diskSize
+}
+
+// Función para validar un EBR encontrado en la imagen: ajuste, estado y punteros coherentes con su posición
+func plausibleEBR(ebr Structs.EBR, position int64, ebrSize int64, diskSize int64) bool {
+	if ebr.PartFit != 'b' && ebr.PartFit != 'f' && ebr.PartFit != 'w' {
+		return false
+	}
+	if ebr.PartMount != 0 && ebr.PartMount != '0' && ebr.PartMount != '1' {
+		return false
+	}
+	if ebr.PartNext != -1 && (ebr.PartNext <= position || ebr.PartNext+ebrSize > diskSize) {
+		return false
+	}
+	if ebr.PartSize == 0 {
+		return ebr.PartStart == position // EBR cabecera vacío
+	}
+	return ebr.PartSize > 0 && ebr.PartStart == position+ebrSize && ebr.PartStart+ebr.PartSize <= diskSize
+}
+
+// Función para reconstruir particiones extendidas a partir de los EBRs encontrados: cada cadena empieza en
+// un EBR al que no apunta ningún otro y la extendida llega hasta el final de su última partición lógica
+func recoverEBRChains(ebrs map[int64]Structs.EBR) []RecoveredPartition {
+	ebrSize := int64(binary.Size(Structs.EBR{}))
+	pointed := make(map[int64]bool)
+	for _, ebr := range ebrs {
+		if _, ok := ebrs[ebr.PartNext]; ok {
+			pointed[ebr.PartNext] = true
+		}
+	}
+
+	var chains []RecoveredPartition
+	for head, first := range ebrs {
+		if pointed[head] {
+			continue
+		}
+
+		chain := RecoveredPartition{Type: "e", Start: head, Source: "ebr", fit: first.PartFit}
+		end := head + ebrSize
+		visited := make(map[int64]bool)
+		for position := head; !visited[position]; {
+			ebr, ok := ebrs[position]
+			if !ok {
+				break
+			}
+			visited[position] = true
+			if ebr.PartSize > 0 {
+				chain.Logicals++
+				end = max(end, ebr.PartStart+ebr.PartSize)
+			}
+			position = ebr.PartNext
+		}
+
+		// Sin particiones lógicas no se puede saber el tamaño que tenía la extendida
+		if chain.Logicals == 0 {
+			continue
+		}
+		chain.Size = end - head
+		chains = append(chains, chain)
+	}
+
+	// Primero las cadenas con más particiones lógicas
+	sort.Slice(chains, func(a, b int) bool {
+		if chains[a].Logicals != chains[b].Logicals {
+			return chains[a].Logicals > chains[b].Logicals
+		}
+		return chains[a].Start < chains[b].Start
+	})
+	return chains
+}
+
+// Función para copiar una partición (primaria o lógica) de un disco a otro. La nueva partición se
+// ubica con Fdisk usando el mismo tamaño y el Superblock copiado se ajusta a su nueva posición.
+func CopyPartition(srcPath string, srcName string, destPath string, name string, type_ string) error {
+	fmt.Println("======Start CPART======")
+	fmt.Println("Src:", srcPath, "Partición:", srcName)
+	fmt.Println("Dest:", destPath, "Nombre:", name, "Tipo:", type_)
+
+	if type_ != "p" && type_ != "l" {
+		fmt.Println("Error: El tipo de la copia debe ser 'p' o 'l'")
+		return fmt.Errorf("el tipo de la copia debe ser 'p' o 'l'")
+	}
+
+	srcFile, err := Utilities.OpenFile(srcPath)
+	if err != nil {
+		fmt.Println("Error: Could not open file at path:", srcPath)
+		return err
+	}
+	defer srcFile.Close()
+
+	source, err := FindPartitionByName(srcFile, srcName)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+	if source.Type == 'e' {
+		fmt.Println("Error: No se puede copiar una partición extendida")
+		return fmt.Errorf("no se puede copiar una partición extendida")
+	}
+
+	destFile, err := Utilities.OpenFile(destPath)
+	if err != nil {
+		fmt.Println("Error: Could not open file at path:", destPath)
+		return err
+	}
+	defer destFile.Close()
+
+	// El nombre debe estar libre en el disco destino para ubicar la copia
+	if _, err := FindPartitionByName(destFile, name); err == nil {
+		fmt.Println("Error: Ya existe una partición con el nombre", name, "en el disco destino")
+		return fmt.Errorf("ya existe una partición con el nombre %s en el disco destino", name)
+	}
+
+	// Crear la partición destino con la ubicación normal de fdisk
+	if err := Fdisk(int(source.Size), destPath, name, "b", type_, ""); err != nil {
+		return fmt.Errorf("no se pudo crear la partición destino: %v", err)
+	}
+	// Si la copia falla se elimina la partición destino para no dejarla a medias en el disco
+	discard := func(err error) error {
+		DeletePartition(destPath, name, "full")
+		return err
+	}
+	destination, err := FindPartitionByName(destFile, name)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return discard(err)
+	}
+
+	// Copiar los datos y ajustar las posiciones absolutas del Superblock
+	if err := Utilities.CopyBytes(srcFile, source.Start, destFile, destination.Start, source.Size); err != nil {
+		fmt.Println("Error al copiar los datos de la partición:", err)
+		return discard(err)
+	}
+	if err := RebaseSuperblock(destFile, destination.Start, destination.Start-source.Start); err != nil {
+		fmt.Println("Error al actualizar el Superblock:", err)
+		return discard(err)
+	}
+
+	fmt.Printf("Partición %s copiada a %s (%s): inicio %d, tamaño %d\n", srcName, name, destPath, destination.Start, destination.Size)
+	fmt.Println("======End CPART======")
+	return nil
+}
+
+// Función para abrir el disco de una partición para exportar o importar su imagen. La partición se indica
+// con el ID de montaje o con la ruta del disco y el nombre; una extendida no se acepta porque sus EBRs
+// guardan posiciones absolutas del disco.
+func openPartitionImage(path string, name string, id string) (*os.File, PartitionLocation, error) {
+	if id != "" {
+		mounted, found := GetMountedPartition(id)
+		if !found {
+			return nil, PartitionLocation{}, fmt.Errorf("no se encontró una partición montada con el ID %s", id)
+		}
+		path = mounted.Path
+	} else if path == "" || name == "" {
+		return nil, PartitionLocation{}, fmt.Errorf("se requiere el ID de la partición o la ruta del disco y el nombre")
+	}
+
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		return nil, PartitionLocation{}, err
+	}
+
+	var partition PartitionLocation
+	if id != "" {
+		partition, err = FindPartitionByID(file, id)
+	} else {
+		partition, err = FindPartitionByName(file, name)
+	}
+	if err == nil && partition.Type == 'e' {
+		err = fmt.Errorf("no se puede exportar ni importar una partición extendida")
+	}
+	if err != nil {
+		file.Close()
+		return nil, PartitionLocation{}, err
+	}
+	return file, partition, nil
+}
+
+// Función para escribir los bytes de una partición en out (el archivo de dumppart o la descarga de la API)
+func DumpPartitionTo(path string, name string, id string, out io.Writer) (PartitionLocation, error) {
+	file, partition, err := openPartitionImage(path, name, id)
+	if err != nil {
+		return PartitionLocation{}, err
+	}
+	defer file.Close()
+
+	// El Superblock guarda posiciones absolutas del disco; en la imagen se guardan relativas al inicio
+	// de la partición para que loadpart las ajuste a la partición destino
+	var image io.Reader = io.NewSectionReader(file, partition.Start, partition.Size)
+	superblockSize := int64(binary.Size(Structs.Superblock{}))
+	var superblock Structs.Superblock
+	if partition.Size >= superblockSize {
+		if err := Utilities.ReadObject(file, &superblock, partition.Start); err == nil && superblock.S_magic == 0xEF53 {
+			shiftSuperblock(&superblock, -partition.Start)
+			var header bytes.Buffer
+			if err := binary.Write(&header, binary.LittleEndian, superblock); err != nil {
+				return PartitionLocation{}, err
+			}
+			image = io.MultiReader(&header, io.NewSectionReader(file, partition.Start+superblockSize, partition.Size-superblockSize))
+		}
+	}
+
+	if _, err := io.Copy(out, image); err != nil {
+		return PartitionLocation{}, fmt.Errorf("no se pudo copiar la partición: %v", err)
+	}
+	return partition, nil
+}
+
+// Función para exportar la imagen de una partición a un archivo del host (dumppart)
+func DumpPartition(path string, name string, id string, out string) error {
+	fmt.Println("======Start DUMPPART======")
+	fmt.Println("Id:", id, "Path:", path, "Name:", name)
+	fmt.Println("Out:", out)
+
+	if out == "" {
+		fmt.Println("Error: El archivo de salida es obligatorio")
+		return fmt.Errorf("el archivo de salida es obligatorio")
+	}
+	if dir := filepath.Dir(out); dir != "" {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			fmt.Println("Error al crear la carpeta de salida:", err)
+			return err
+		}
+	}
+
+	// Escribir primero a un temporal para no dejar una imagen a medias
+	tempPath := out + ".tmp"
+	outFile, err := os.Create(tempPath)
+	if err != nil {
+		fmt.Println("Error al crear el archivo de salida:", err)
+		return err
+	}
+	partition, err := DumpPartitionTo(path, name, id, outFile)
+	if closeErr := outFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, out)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		fmt.Println("Error:", err)
+		return err
+	}
+
+	fmt.Printf("Partición %s exportada a %s: %d bytes\n", partition.Name, out, partition.Size)
+	fmt.Println("======End DUMPPART======")
+	return nil
+}
+
+// Función para importar una imagen del host en una partición existente (loadpart). La imagen no puede ser
+// más grande que la partición y la partición no puede estar montada. Si la imagen tiene un sistema de archivos,
+// su Superblock se ajusta a la posición de la partición, como en cpart.
+func LoadPartition(path string, name string, in string) error {
+	fmt.Println("======Start LOADPART======")
+	fmt.Println("Path:", path, "Name:", name)
+	fmt.Println("In:", in)
+
+	image, err := os.Open(in)
+	if err != nil {
+		fmt.Println("Error al abrir la imagen:", err)
+		return fmt.Errorf("no se pudo abrir la imagen %s: %v", in, err)
+	}
+	defer image.Close()
+
+	stat, err := image.Stat()
+	if err != nil {
+		return err
+	}
+
+	file, partition, err := openPartitionImage(path, name, "")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+	defer file.Close()
+
+	if partition.Status == '1' && isMountedID(partition.Id) {
+		fmt.Println("Error: La partición está montada, desmóntela antes de importar la imagen")
+		return fmt.Errorf("la partición %s está montada (%s), desmóntela antes de importar la imagen", partition.Name, partition.Id)
+	}
+	if stat.Size() > partition.Size {
+		fmt.Printf("Error: La imagen (%d bytes) es más grande que la partición (%d bytes)\n", stat.Size(), partition.Size)
+		return fmt.Errorf("la imagen (%d bytes) es más grande que la partición %s (%d bytes)", stat.Size(), partition.Name, partition.Size)
+	}
+
+	// Si la imagen tiene un sistema de archivos, sus áreas deben caber en la partición destino
+	formatted, err := checkImageSuperblock(image, partition.Size)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+
+	if _, err := io.Copy(io.NewOffsetWriter(file, partition.Start), image); err != nil {
+		fmt.Println("Error al escribir la imagen:", err)
+		return fmt.Errorf("no se pudo escribir la imagen en la partición: %v", err)
+	}
+
+	// Las posiciones del Superblock de la imagen son relativas al inicio de la partición
+	if formatted {
+		if err := RebaseSuperblock(file, partition.Start, partition.Start); err != nil {
+			fmt.Println("Error al actualizar el Superblock:", err)
+			return err
+		}
+	}
+
+	fmt.Printf("Imagen %s importada en la partición %s: %d de %d bytes\n", in, partition.Name, stat.Size(), partition.Size)
+	fmt.Println("======End LOADPART======")
+	return nil
+}
+
+// Función para revisar el Superblock de una imagen de dumppart: devuelve si la imagen tiene un sistema de
+// archivos y un error si sus áreas no están en orden o no caben en una partición de partitionSize bytes
+func checkImageSuperblock(image io.ReaderAt, partitionSize int64) (bool, error) {
+	var superblock Structs.Superblock
+	superblockSize := int64(binary.Size(superblock))
+	if err := binary.Read(io.NewSectionReader(image, 0, superblockSize), binary.LittleEndian, &superblock); err != nil || superblock.S_magic != 0xEF53 {
+		return false, nil // La imagen no tiene sistema de archivos, se copia tal cual
+	}
+
+	blocksEnd := superblock.S_block_start + int64(superblock.S_blocks_count)*int64(superblock.S_block_size)
+	if superblock.S_inodes_count <= 0 || superblock.S_blocks_count <= 0 ||
+		superblock.S_bm_inode_start < superblockSize ||
+		superblock.S_bm_block_start < superblock.S_bm_inode_start ||
+		superblock.S_inode_start < superblock.S_bm_block_start ||
+		superblock.S_block_start < superblock.S_inode_start ||
+		blocksEnd > partitionSize {
+		return true, fmt.Errorf("el sistema de archivos de la imagen tiene posiciones fuera de la partición (termina en %d de %d bytes)", blocksEnd, partitionSize)
+	}
+	return true, nil
+}
+
+// Partición del formato anterior junto con su ubicación en el formato actual
+type migrationItem struct {
+	name       string
+	index      int   // Índice de la entrada en la tabla de particiones
+	oldStart   int64 // Inicio de los datos en el formato anterior
+	size       int64 // Tamaño en el formato anterior
+	newStart   int64
+	newSize    int64
+	formatted  bool // Tiene un Superblock del formato anterior al inicio
+	superblock Structs.SuperblockV1
+	logicals   []legacyLogical // Solo para la partición extendida
+}
+
+// EBR del formato anterior (ya convertido a la estructura actual) junto con su ubicación en el formato actual
+type legacyLogical struct {
+	oldPosition int64
+	newPosition int64
+	ebr         Structs.EBR
+	data        migrationItem // Datos de la lógica (vacío en un EBR cabecera sin partición)
+}
+
+// Disco del formato anterior ya leído y validado
+type legacyDisk struct {
+	mbr   Structs.MRB // MBR convertido a la estructura actual, con las posiciones del formato anterior
+	items []migrationItem
+}
+
+// Función para leer el MBR del formato anterior y convertirlo a la estructura actual
+func readMBRv1(file *os.File) (Structs.MRB, error) {
+	var legacy Structs.MRBv1
+	if err := Utilities.ReadObject(file, &legacy, 0); err != nil {
+		return Structs.MRB{}, err
+	}
+	mbr := Structs.MRB{
+		Version:      Structs.FormatVersion,
+		MbrSize:      int64(legacy.MbrSize),
+		CreationDate: legacy.CreationDate,
+		Signature:    legacy.Signature,
+		Fit:          legacy.Fit,
+	}
+	for i, partition := range legacy.Partitions {
+		mbr.Partitions[i] = Structs.Partition{
+			Status:      partition.Status,
+			Type:        partition.Type,
+			Fit:         partition.Fit,
+			Start:       int64(partition.Start),
+			Size:        int64(partition.Size),
+			Name:        partition.Name,
+			Correlative: partition.Correlative,
+		}
+		copy(mbr.Partitions[i].Id[:], partition.Id[:])
+	}
+	return mbr, nil
+}
+
+// Función para leer un EBR del formato anterior y convertirlo a la estructura actual
+func readEBRv1(file *os.File, position int64) (Structs.EBR, error) {
+	var legacy Structs.EBRv1
+	if err := Utilities.ReadObject(file, &legacy, position); err != nil {
+		return Structs.EBR{}, err
+	}
+	return Structs.EBR{
+		PartMount: legacy.PartMount,
+		PartFit:   legacy.PartFit,
+		PartStart: int64(legacy.PartStart),
+		PartSize:  int64(legacy.PartSize),
+		PartNext:  int64(legacy.PartNext),
+		PartName:  legacy.PartName,
+	}, nil
+}
+
+// Función para leer las particiones de un disco del formato anterior, validando que tengan sentido
+// Si alguna validación falla el disco no se puede migrar sin riesgo de dañarlo
+func readLegacyDisk(file *os.File) (legacyDisk, error) {
+	var disk legacyDisk
+	mbr, err := readMBRv1(file)
+	if err != nil {
+		return disk, fmt.Errorf("no se pudo leer el MBR: %v", err)
+	}
+	disk.mbr = mbr
+
+	// Validar las entradas del MBR
+	mbrSize := int64(binary.Size(Structs.MRBv1{}))
+	var used []FreeSpace
+	extended := 0
+	for i, partition := range mbr.Partitions {
+		if partition.Size == 0 {
+			continue
+		}
+		switch {
+		case partition.Size < 0:
+			return disk, fmt.Errorf("la partición %d tiene un tamaño negativo", i+1)
+		case partition.Type[0] != 'p' && partition.Type[0] != 'e':
+			return disk, fmt.Errorf("la partición %d tiene un tipo inválido", i+1)
+		case !validLegacyStatus(partition.Status[0]) || !validLegacyFit(partition.Fit[0]):
+			return disk, fmt.Errorf("la partición %d tiene un estado o ajuste inválido", i+1)
+		case partition.Start < mbrSize || partition.Start+partition.Size > mbr.MbrSize:
+			return disk, fmt.Errorf("la partición %d está fuera del disco", i+1)
+		}
+		if partition.Type[0] == 'e' {
+			extended++
+		}
+		used = append(used, FreeSpace{Start: partition.Start, Size: partition.Size})
+	}
+	if err := checkLegacyOverlaps(used); err != nil {
+		return disk, err
+	}
+	if extended > 1 {
+		return disk, fmt.Errorf("el disco tiene más de una partición extendida")
+	}
+
+	for i, partition := range mbr.Partitions {
+		if partition.Size == 0 {
+			continue
+		}
+		name := strings.TrimRight(string(partition.Name[:]), "\x00")
+		if partition.Type[0] != 'e' {
+			item, err := readLegacyItem(file, name, i, partition.Start, partition.Size)
+			if err != nil {
+				return disk, err
+			}
+			disk.items = append(disk.items, item)
+			continue
+		}
+
+		item := migrationItem{name: name, index: i, oldStart: partition.Start, size: partition.Size}
+		if item.logicals, err = readLegacyEBRChain(file, partition.Start, partition.Size); err != nil {
+			return disk, err
+		}
+		disk.items = append(disk.items, item)
+	}
+	return disk, nil
+}
+
+// Función para validar el estado de una partición o EBR del formato anterior (sin asignar, '0' o '1')
+func validLegacyStatus(status byte) bool {
+	return status == 0 || status == '0' || status == '1'
+}
+
+// Función para validar el ajuste de una partición o EBR del formato anterior
+func validLegacyFit(fit byte) bool {
+	return fit == 'b' || fit == 'f' || fit == 'w'
+}
+
+// Función para verificar que las particiones del formato anterior no se solapen
+func checkLegacyOverlaps(used []FreeSpace) error {
+	sort.Slice(used, func(a, b int) bool {
+		return used[a].Start < used[b].Start
+	})
+	for i := 1; i < len(used); i++ {
+		if used[i].Start < used[i-1].Start+used[i-1].Size {
+			return fmt.Errorf("las particiones en %d y %d se solapan", used[i-1].Start, used[i].Start)
+		}
+	}
+	return nil
+}
+
+// Función para leer una partición del formato anterior y detectar si tiene un sistema de archivos
+// Un Superblock cuyas posiciones no caben en la partición no se puede migrar
+func readLegacyItem(file *os.File, name string, index int, start int64, size int64) (migrationItem, error) {
+	item := migrationItem{name: name, index: index, oldStart: start, size: size}
+	superblockSize := int64(binary.Size(Structs.SuperblockV1{}))
+	if size < superblockSize {
+		return item, nil
+	}
+	if err := Utilities.ReadObject(file, &item.superblock, start); err != nil || item.superblock.S_magic != 0xEF53 {
+		return item, nil
+	}
+	item.formatted = true
+
+	superblock := item.superblock
+	blocksEnd := int64(superblock.S_block_start) + int64(superblock.S_blocks_count)*int64(superblock.S_block_size)
+	if superblock.S_inodes_count <= 0 || superblock.S_blocks_count <= 0 ||
+		int64(superblock.S_bm_inode_start) < start+superblockSize ||
+		superblock.S_bm_block_start < superblock.S_bm_inode_start ||
+		superblock.S_inode_start < superblock.S_bm_block_start ||
+		superblock.S_block_start < superblock.S_inode_start ||
+		blocksEnd > start+size {
+		return item, fmt.Errorf("el sistema de archivos de la partición %s tiene posiciones fuera de la partición", name)
+	}
+	return item, nil
+}
+
+// Función para leer la cadena de EBRs del formato anterior
+// Los datos de cada lógica van justo después de su EBR y la cadena avanza siempre hacia el final
+// de la extendida; solo el primer EBR puede ser una cabecera sin partición
+func readLegacyEBRChain(file *os.File, start int64, size int64) ([]legacyLogical, error) {
+	var chain []legacyLogical
+	end := start + size
+	ebrSize := int64(binary.Size(Structs.EBRv1{}))
+
+	ebrPos := start
+	for ebrPos != -1 {
+		if ebrPos < start || ebrPos+ebrSize > end {
+			return nil, fmt.Errorf("EBR fuera de la partición extendida en la posición %d", ebrPos)
+		}
+
+		ebr, err := readEBRv1(file, ebrPos)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !validLegacyStatus(ebr.PartMount) || !validLegacyFit(ebr.PartFit):
+			return nil, fmt.Errorf("el EBR en la posición %d tiene un estado o ajuste inválido", ebrPos)
+		case ebr.PartSize < 0 || (ebr.PartSize == 0 && ebrPos != start):
+			return nil, fmt.Errorf("el EBR en la posición %d no tiene una partición válida", ebrPos)
+		case ebr.PartSize > 0 && ebr.PartStart != ebrPos+ebrSize:
+			return nil, fmt.Errorf("los datos del EBR en la posición %d no empiezan después de un EBR de %d bytes", ebrPos, ebrSize)
+		case ebr.PartSize > 0 && ebr.PartStart+ebr.PartSize > end:
+			return nil, fmt.Errorf("la lógica del EBR en la posición %d sale de la partición extendida", ebrPos)
+		case ebr.PartNext != -1 && ebr.PartNext < ebrPos+ebrSize+ebr.PartSize:
+			return nil, fmt.Errorf("el EBR en la posición %d apunta hacia atrás o dentro de su propia lógica", ebrPos)
+		}
+
+		logical := legacyLogical{oldPosition: ebrPos, ebr: ebr}
+		if ebr.PartSize > 0 {
+			name := strings.TrimRight(string(ebr.PartName[:]), "\x00")
+			if logical.data, err = readLegacyItem(file, name, -1, ebr.PartStart, ebr.PartSize); err != nil {
+				return nil, err
+			}
+		}
+		chain = append(chain, logical)
+		ebrPos = ebr.PartNext
+	}
+
+	return chain, nil
+}
+
+// Función para ubicar los datos de una partición en el formato actual a partir de un cursor
+// Nunca se mueve hacia el inicio, y un Superblock migrado necesita los bytes extra del formato actual
+func placeMigrationItem(item *migrationItem, cursor int64) int64 {
+	item.newStart = max(item.oldStart, cursor)
+	item.newSize = item.size
+	if item.formatted {
+		item.newSize += int64(binary.Size(Structs.Superblock{}) - binary.Size(Structs.SuperblockV1{}))
+	}
+	return item.newStart + item.newSize
+}
+
+// Función para convertir un Superblock del formato anterior desplazando sus posiciones absolutas
+func migrateSuperblock(old Structs.SuperblockV1, shift int64) Structs.Superblock {
+	return Structs.Superblock{
+		S_filesystem_type:   old.S_filesystem_type,
+		S_inodes_count:      old.S_inodes_count,
+		S_blocks_count:      old.S_blocks_count,
+		S_free_blocks_count: old.S_free_blocks_count,
+		S_free_inodes_count: old.S_free_inodes_count,
+		S_mtime:             old.S_mtime,
+		S_umtime:            old.S_umtime,
+		S_mnt_count:         old.S_mnt_count,
+		S_magic:             old.S_magic,
+		S_inode_size:        old.S_inode_size,
+		S_block_size:        old.S_block_size,
+		S_fist_ino:          old.S_fist_ino,
+		S_first_blo:         old.S_first_blo,
+		S_bm_inode_start:    int64(old.S_bm_inode_start) + shift,
+		S_bm_block_start:    int64(old.S_bm_block_start) + shift,
+		S_inode_start:       int64(old.S_inode_start) + shift,
+		S_block_start:       int64(old.S_block_start) + shift,
+	}
+}
+
+// Función para obtener el área de datos que se mueve al migrar una partición
+// El Superblock no se mueve, se vuelve a escribir en el formato actual
+func migrationMove(item migrationItem) (src int64, dst int64, size int64) {
+	if !item.formatted {
+		return item.oldStart, item.newStart, item.size
+	}
+	oldSuperblockSize := int64(binary.Size(Structs.SuperblockV1{}))
+	newSuperblockSize := int64(binary.Size(Structs.Superblock{}))
+	return item.oldStart + oldSuperblockSize, item.newStart + newSuperblockSize, item.size - oldSuperblockSize
+}
+
+// Función para migrar un disco del formato anterior (32 bits) al formato actual (64 bits) en el mismo archivo
+// Las estructuras del formato actual son más grandes, por lo que las particiones se desplazan
+// hacia el final del disco solo lo necesario, usando el espacio libre que haya entre ellas
+func MigrateDisk(path string) error {
+	fmt.Println("======Start MIGRATE======")
+	fmt.Println("Path:", path)
+
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		fmt.Println("Error: Could not open file at path:", path)
+		return err
+	}
+	defer file.Close()
+
+	if !hasLegacyHeader(file) {
+		var mbr Structs.MRB
+		if err := Utilities.ReadObject(file, &mbr, 0); err == nil && mbr.Version == Structs.FormatVersion {
+			fmt.Println("El disco ya usa la versión", Structs.FormatVersion, "del formato, no hay nada que migrar")
+			fmt.Println("======End MIGRATE======")
+			return nil
+		}
+		fmt.Println("Error: El disco no tiene un formato reconocido")
+		return fmt.Errorf("el disco %s no tiene un formato reconocido", path)
+	}
+
+	// No se puede migrar un disco con particiones montadas
+	diskID, err := generateDiskID(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+	if partitions := mountedPartitions[diskID]; len(partitions) > 0 {
+		fmt.Println("Error: El disco tiene particiones montadas, desmóntelas antes de migrarlo")
+		return fmt.Errorf("el disco tiene particiones montadas, desmóntelas antes de migrarlo")
+	}
+
+	// Leer las particiones del formato anterior; si no tienen sentido no se escribe nada
+	legacy, err := readLegacyDisk(file)
+	if err != nil {
+		fmt.Println("Error: No se puede migrar el disco:", err)
+		return fmt.Errorf("no se puede migrar el disco: %v", err)
+	}
+
+	mbr := legacy.mbr
+	items := legacy.items
+	ebrSize := int64(binary.Size(Structs.EBR{}))
+
+	// Las particiones empiezan después del MBR actual
+	cursor := int64(binary.Size(mbr))
+	lastUsable := mbr.MbrSize
+
+	// Calcular la nueva ubicación de cada partición (y de cada lógica dentro de la extendida)
+	sort.Slice(items, func(a, b int) bool {
+		return items[a].oldStart < items[b].oldStart
+	})
+	for i := range items {
+		item := &items[i]
+		if item.logicals == nil {
+			cursor = placeMigrationItem(item, cursor)
+			continue
+		}
+
+		item.newStart = max(item.oldStart, cursor)
+		inner := item.newStart
+		for j := range item.logicals {
+			logical := &item.logicals[j]
+			logical.newPosition = max(logical.oldPosition, inner)
+			inner = logical.newPosition + ebrSize
+			if logical.ebr.PartSize > 0 {
+				inner = placeMigrationItem(&logical.data, inner)
+			}
+		}
+		// La extendida conserva su final si el espacio libre que tiene dentro alcanza
+		item.newSize = max(item.oldStart+item.size, inner) - item.newStart
+		cursor = item.newStart + item.newSize
+	}
+	if cursor > lastUsable {
+		fmt.Printf("Error: Faltan %d bytes libres al final del disco para migrarlo\n", cursor-lastUsable)
+		return fmt.Errorf("faltan %d bytes libres al final del disco para migrarlo", cursor-lastUsable)
+	}
+
+	// Mover los datos de la última partición a la primera, ningún dato se mueve hacia el inicio
+	var moves []migrationItem
+	for _, item := range items {
+		if item.logicals == nil {
+			moves = append(moves, item)
+		}
+		for _, logical := range item.logicals {
+			if logical.ebr.PartSize > 0 {
+				moves = append(moves, logical.data)
+			}
+		}
+	}
+	for i := len(moves) - 1; i >= 0; i-- {
+		src, dst, size := migrationMove(moves[i])
+		if src != dst {
+			fmt.Printf("Moviendo la partición %s de %d a %d\n", moves[i].name, moves[i].oldStart, moves[i].newStart)
+		}
+		if err := Utilities.MoveBytes(file, src, dst, size); err != nil {
+			fmt.Println("Error al mover la partición:", err)
+			return err
+		}
+	}
+
+	// Escribir los Superblocks y los EBRs en el formato actual
+	for _, item := range moves {
+		if item.formatted {
+			superblock := migrateSuperblock(item.superblock, item.newStart-item.oldStart+item.newSize-item.size)
+			if err := Utilities.WriteObject(file, superblock, item.newStart); err != nil {
+				return err
+			}
+		}
+	}
+	for _, item := range items {
+		for j, logical := range item.logicals {
+			ebr := Structs.EBR{
+				PartMount: logical.ebr.PartMount,
+				PartFit:   logical.ebr.PartFit,
+				PartStart: logical.newPosition,
+				PartSize:  logical.data.newSize,
+				PartNext:  -1,
+				PartName:  logical.ebr.PartName,
+				PartId:    logical.ebr.PartId,
+			}
+			if logical.ebr.PartSize > 0 {
+				ebr.PartStart = logical.data.newStart
+			}
+			if j+1 < len(item.logicals) {
+				ebr.PartNext = item.logicals[j+1].newPosition
+			}
+			if err := Utilities.WriteObject(file, ebr, logical.newPosition); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Actualizar la tabla de particiones; el MBR se escribe al final para que el disco
+	// solo pase a la nueva versión cuando todo lo demás ya se migró
+	for _, item := range items {
+		mbr.Partitions[item.index].Start = item.newStart
+		mbr.Partitions[item.index].Size = item.newSize
+	}
+	if err := Utilities.WriteObject(file, mbr, 0); err != nil {
+		fmt.Println("Error: Could not write MBR to file")
+		return err
+	}
+
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+	fmt.Printf("Disco migrado a la versión %d del formato\n", Structs.FormatVersion)
+	table.Print()
+	if extended, ok := table.Extended(); ok {
+		PrintEBRChain(file, extended)
+	}
+
+	fmt.Println("======End MIGRATE======")
+	return nil
+}
+
+// Estructura con la ubicación de una partición (primaria, extendida o lógica) dentro del disco
+type PartitionLocation struct {
+	Name        string
+	Type        byte // 'p', 'e' o 'l'
+	Fit         byte
+	Start       int64 // Inicio de los datos de la partición
+	Size        int64
+	Status      byte   // '1' si la partición está montada
+	Id          string // ID de montaje
+	Index       int    // Posición en la tabla del MBR (primarias y extendidas)
+	EBRPosition int64  // Posición del EBR (solo lógicas)
+}
+
+// Función para recorrer las particiones del disco (incluidas las lógicas) hasta encontrar la que cumpla la condición
+func findPartition(file *os.File, table PartitionTable, match func(PartitionLocation) bool) (PartitionLocation, bool) {
+	for _, location := range table.Entries() {
+		if match(location) {
+			return location, true
+		}
+
+		// Buscar también en las particiones lógicas de la extendida
+		if location.Type == 'e' {
+			extended, _ := table.Extended()
+			chain, err := ReadEBRChain(file, extended)
+			if err != nil {
+				fmt.Println("Error al leer EBR:", err)
+			}
+			for _, logical := range chain {
+				if logical.EBR.PartSize <= 0 {
+					continue // EBR vacío
+				}
+				location := PartitionLocation{
+					Name:        strings.TrimRight(string(logical.EBR.PartName[:]), "\x00"),
+					Type:        'l',
+					Fit:         logical.EBR.PartFit,
+					Start:       logical.EBR.PartStart,
+					Size:        logical.EBR.PartSize,
+					Status:      logical.EBR.PartMount,
+					Id:          strings.TrimRight(string(logical.EBR.PartId[:]), "\x00"),
+					Index:       -1,
+					EBRPosition: logical.Position,
+				}
+				if match(location) {
+					return location, true
+				}
+			}
+		}
+	}
+
+	return PartitionLocation{}, false
+}
+
+// Función para normalizar un nombre o ID de partición antes de compararlo: se ignoran los bytes nulos,
+// los espacios y las comillas de los extremos, y no se distinguen mayúsculas de minúsculas
+func normalizePartitionKey(key string) string {
+	return strings.ToLower(strings.Trim(key, "\x00 \""))
+}
+
+// Función para validar el nombre de una partición nueva: no vacío, que quepa en la tabla (o en el EBR) y sin repetirse
+func validatePartitionName(file *os.File, table PartitionTable, name string, type_ string) error {
+	if normalizePartitionKey(name) == "" {
+		return fmt.Errorf("el nombre de la partición no puede estar vacío")
+	}
+
+	maxLength := len(Structs.Partition{}.Name)
+	if table.Kind() == "gpt" && type_ != "l" {
+		maxLength = len(Structs.GPTEntry{}.Name)
+	}
+	if len(name) > maxLength {
+		return fmt.Errorf("el nombre de la partición excede los %d caracteres", maxLength)
+	}
+
+	_, inUse := findPartition(file, table, func(location PartitionLocation) bool {
+		return SamePartitionKey(location.Name, name)
+	})
+	if inUse {
+		return fmt.Errorf("ya existe una partición con el nombre %s en el disco", name)
+	}
+	return nil
+}
+
+// Función para saber si dos nombres o IDs de partición son iguales con las reglas de búsqueda
+func SamePartitionKey(a string, b string) bool {
+	key := normalizePartitionKey(a)
+	return key != "" && key == normalizePartitionKey(b)
+}
+
+// Función para resolver una partición del disco (primaria, extendida o lógica) por nombre o por ID de montaje.
+// Todas las búsquedas de particiones pasan por aquí: la comparación es exacta (nunca por prefijo) y no
+// distingue mayúsculas. Si más de una partición coincide se devuelve un error en lugar de elegir una.
+func resolvePartition(file *os.File, table PartitionTable, key string, byID bool) (PartitionLocation, error) {
+	var matches []PartitionLocation
+	findPartition(file, table, func(location PartitionLocation) bool {
+		value := location.Name
+		if byID {
+			value = location.Id
+		}
+		if SamePartitionKey(value, key) {
+			matches = append(matches, location)
+		}
+		return false // Recorrer todas las particiones para detectar duplicados
+	})
+
+	field := "el nombre"
+	if byID {
+		field = "el ID"
+	}
+	if len(matches) == 0 {
+		return PartitionLocation{}, fmt.Errorf("no se encontró la partición con %s %s en el disco", field, key)
+	}
+	if len(matches) > 1 {
+		return PartitionLocation{}, fmt.Errorf("hay %d particiones con %s %s en el disco, use fdisk -check para revisarlo", len(matches), field, key)
+	}
+	return matches[0], nil
+}
+
+// Función para buscar en el disco la partición (primaria o lógica) con el ID de montaje indicado
+func FindPartitionByID(file *os.File, id string) (PartitionLocation, error) {
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		return PartitionLocation{}, err
+	}
+	return resolvePartition(file, table, id, true)
+}
+
+// Función para buscar en el disco la partición (primaria, extendida o lógica) con el nombre indicado
+func FindPartitionByName(file *os.File, name string) (PartitionLocation, error) {
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		return PartitionLocation{}, err
+	}
+	return resolvePartition(file, table, name, false)
+}
+
+// Función para ubicar un ID en la tabla de particiones montadas: devuelve el disco y la posición de la partición
+func findMountedPartition(id string) (string, int, bool) {
+	for diskID, partitions := range mountedPartitions {
+		for i, partition := range partitions {
+			if SamePartitionKey(partition.ID, id) {
+				return diskID, i, true
+			}
+		}
+	}
+	return "", -1, false
+}
+
+// Función para obtener una partición de la tabla de particiones montadas a partir de su ID
+func GetMountedPartition(id string) (MountedPartition, bool) {
+	diskID, index, found := findMountedPartition(id)
+	if !found {
+		return MountedPartition{}, false
+	}
+	return mountedPartitions[diskID][index], true
+}
+
+// Función para actualizar el estado de montaje y el ID de una partición en la tabla de particiones o en su EBR
+func setPartitionMount(file *os.File, table PartitionTable, location PartitionLocation, status byte, id string) error {
+	if location.Type == 'l' {
+		var ebr Structs.EBR
+		if err := Utilities.ReadObject(file, &ebr, location.EBRPosition); err != nil {
+			return err
+		}
+		ebr.PartMount = status
+		ebr.PartId = [16]byte{}
+		copy(ebr.PartId[:], id)
+		return Utilities.WriteObject(file, ebr, location.EBRPosition)
+	}
+
+	location.Status = status
+	location.Id = id
+	table.Update(location)
+	return table.Write(file)
+}
+
+// Función para montar particiones (primarias o lógicas)
+func Mount(path string, name string) {
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		fmt.Println("Error: No se pudo abrir el archivo en la ruta:", path)
+		return
+	}
+	defer file.Close()
+
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error: No se pudo leer la tabla de particiones:", err)
+		return
+	}
+
+	fmt.Printf("Buscando partición con nombre: '%s'\n", name)
+
+	partition, err := resolvePartition(file, table, name, false)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if partition.Type == 'e' {
+		fmt.Println("Error: No se puede montar una partición extendida")
+		return
+	}
+
+	// Verificar si la partición ya está montada. Si el disco la marca como montada pero su ID no
+	// está en la tabla de montajes, es un montaje huérfano de una sesión anterior y se vuelve a montar.
+	if partition.Status == '1' {
+		if isMountedID(partition.Id) {
+			fmt.Println("Error: La partición ya está montada")
+			return
+		}
+		fmt.Printf("Advertencia: La partición estaba marcada como montada (ID %s) en una sesión anterior, se montará de nuevo\n", partition.Id)
+	}
+
+	// Identificar el disco por la firma de su MBR
+	diskID, err := generateDiskID(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if err := checkDiskIdentity(diskID, path); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	// Generar un ID único: prefijo configurado + correlativo del disco + letra del disco
+	partitionID, err := nextMountID(diskID, path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	// Actualizar el estado de la partición a montada y asignar el ID (en el MBR o en el EBR)
+	if err := setPartitionMount(file, table, partition, '1', partitionID); err != nil {
+		fmt.Println("Error: No se pudo actualizar la partición en el disco")
+		return
+	}
+
+	mountedPartitions[diskID] = append(mountedPartitions[diskID], MountedPartition{
+		Path:   path,
+		Name:   partition.Name,
+		ID:     partitionID,
+		Status: '1',
+	})
+
+	persistMountRegistry()
+
+	fmt.Printf("Partición montada con ID: %s\n", partitionID)
+
+	fmt.Println("")
+	// Imprimir la tabla de particiones actualizada
+	fmt.Println("Tabla de particiones actualizada:")
+	table.Print()
+	if extended, ok := table.Extended(); ok && partition.Type == 'l' {
+		PrintEBRChain(file, extended)
+	}
+	fmt.Println("")
+
+	// Imprimir las particiones montadas (solo estan mientras dure la sesion de la consola)
+	PrintMountedPartitions()
+}
+
+// Función para obtener la posición de la partición extendida en el MBR (-1 si no existe)
+func extendedIndex(mbr Structs.MRB) int {
+	for i := 0; i < 4; i++ {
+		if mbr.Partitions[i].Size > 0 && mbr.Partitions[i].Type[0] == 'e' {
+			return i
+		}
+	}
+	return -1
+}
+
+func Unmount(id string) {
+	fmt.Println("Desmontando partición con ID:", id)
+
+	// Buscar la partición montada por ID
+	diskID, partitionIndex, found := findMountedPartition(id)
+
+	// Si no se encuentra la partición, mostrar un error
+	if !found {
+		fmt.Println("Error: No se encontró una partición montada con el ID proporcionado:", id)
+		return
+	}
+
+	partitionFound := mountedPartitions[diskID][partitionIndex]
+
+	// Abrir el archivo del disco correspondiente
+	file, err := Utilities.OpenFile(partitionFound.Path)
+	if err != nil {
+		fmt.Println("Error: No se pudo abrir el archivo en la ruta:", partitionFound.Path)
+		return
+	}
+	defer file.Close()
+
+	// Leer la tabla de particiones
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		fmt.Println("Error: No se pudo leer la tabla de particiones:", err)
+		return
+	}
+
+	// Buscar la partición (primaria o lógica) en el disco utilizando su ID
+	partition, err := resolvePartition(file, table, partitionFound.ID, true)
+	if err != nil {
+		fmt.Println("Error: No se pudo encontrar la partición en el disco para desmontar:", err)
+		return
+	}
+
+	// Cambiar el estado de la partición de montada ('1') a desmontada ('0') y borrar su ID
+	if err := setPartitionMount(file, table, partition, '0', ""); err != nil {
+		fmt.Println("Error: No se pudo sobrescribir la partición en el disco")
+		return
+	}
+
+	// Eliminar la partición de la lista de particiones montadas
+	mountedPartitions[diskID] = append(mountedPartitions[diskID][:partitionIndex], mountedPartitions[diskID][partitionIndex+1:]...)
+
+	// Si ya no hay particiones montadas en este disco, eliminar el disco de la lista
+	if len(mountedPartitions[diskID]) == 0 {
+		delete(mountedPartitions, diskID)
+	}
+
+	persistMountRegistry()
+
+	fmt.Println("Partición desmontada con éxito.")
+	PrintMountedPartitions() // Mostrar las particiones montadas restantes
+}
+
+// Función para obtener el ID de un disco a partir de la firma de su MBR, de modo que el disco se reconoce
+// aunque se abra con otra ruta (enlaces simbólicos, rutas relativas o con otras mayúsculas)
+func generateDiskID(path string) (string, error) {
+	signature, err := readDiskSignature(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d", signature), nil
+}
+
+// Función para leer la firma del MBR de un disco (también de los discos con el formato anterior)
+func readDiskSignature(path string) (int32, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("no se pudo abrir el disco %s: %v", path, err)
+	}
+	defer file.Close()
+
+	// Un archivo más pequeño que cualquier MBR no es un disco
+	var mbr Structs.MRB
+	stat, err := file.Stat()
+	if err != nil || stat.Size() < int64(binary.Size(Structs.MRBv1{})) {
+		return 0, fmt.Errorf("el archivo %s no es un disco", path)
+	}
+	if err := Utilities.ReadObject(file, &mbr, 0); err != nil {
+		return 0, fmt.Errorf("no se pudo leer el MBR del disco %s: %v", path, err)
+	}
+	// El MBR guarda el tamaño del disco, así se descartan otros archivos (como las imágenes de dumppart)
+	if mbr.Version == Structs.FormatVersion && mbr.MbrSize == stat.Size() {
+		return mbr.Signature, nil
+	}
+	if hasLegacyHeader(file) {
+		var legacy Structs.MRBv1
+		if err := Utilities.ReadObject(file, &legacy, 0); err != nil {
+			return 0, fmt.Errorf("no se pudo leer el MBR del disco %s: %v", path, err)
+		}
+		return legacy.Signature, nil
+	}
+	return 0, fmt.Errorf("el disco %s no tiene un formato reconocido", path)
+}
+
+// Función para verificar que el disco de la ruta es el mismo archivo que ya se conoce con su firma.
+// Dos archivos distintos con la misma firma (por ejemplo, una copia del disco) no pueden montarse a la vez.
+func checkDiskIdentity(diskID string, path string) error {
+	info, ok := diskMountInfos[diskID]
+	if !ok || info.Path == path {
+		return nil
+	}
+	known, err := os.Stat(info.Path)
+	if err != nil {
+		return nil // El disco conocido ya no existe, la firma queda libre
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(known, current) {
+		return fmt.Errorf("el disco %s tiene la misma firma (%s) que el disco %s, que ya tiene la letra %s", path, diskID, info.Path, info.Letter)
+	}
+	return nil
+}
+
+// Función para generar la firma de un disco nuevo sin repetir la de otro disco conocido: los discos
+// con letra asignada y los discos de la misma carpeta
+func newDiskSignature(path string) int32 {
+	used := make(map[int32]bool)
+	for diskID := range diskMountInfos {
+		var signature int32
+		if _, err := fmt.Sscanf(diskID, "%d", &signature); err == nil {
+			used[signature] = true
+		}
+	}
+	if entries, err := os.ReadDir(filepath.Dir(path)); err == nil {
+		for _, entry := range entries {
+			sibling := filepath.Join(filepath.Dir(path), entry.Name())
+			if entry.IsDir() || sibling == filepath.Clean(path) {
+				continue
+			}
+			if signature, err := readDiskSignature(sibling); err == nil {
+				used[signature] = true
+			}
+		}
+	}
+
+	for {
+		signature := rand.Int31() // rand.Int31() genera solo números no negativos
+		if !used[signature] {
+			return signature
+		}
+		fmt.Println("La firma generada ya pertenece a otro disco, se genera otra")
+	}
+}
+
+// Carpeta donde se guardan las copias (snapshots) de los discos
+var snapshotsDir = "snapshots"
+
+// Partición montada guardada en un snapshot, incluyendo si tenía una sesión iniciada
+type SnapshotMount struct {
+	Name     string `json:"name"`
+	ID       string `json:"id"`
+	LoggedIn bool   `json:"logged_in"`
+	User     string `json:"user,omitempty"`
+	UID      int32  `json:"uid,omitempty"`
+	GID      int32  `json:"gid,omitempty"`
+}
+
+// Información de un snapshot: la imagen del disco más el estado de montaje del disco al crearlo
+type SnapshotInfo struct {
+	Name      string          `json:"name"`
+	DiskPath  string          `json:"disk_path"`
+	CreatedAt string          `json:"created_at"`
+	Size      int64           `json:"size"`
+	Mounts    []SnapshotMount `json:"mounts"`
+	Disk      *diskMountInfo  `json:"disk,omitempty"` // Letra y correlativo del disco
+}
+
+// Función para configurar la carpeta de los snapshots (vacío conserva la carpeta por defecto)
+func SetSnapshotsDir(dir string) {
+	if dir != "" {
+		snapshotsDir = dir
+	}
+}
+
+// Función para validar el nombre de un snapshot (se usa como nombre de archivo)
+func validateSnapshotName(name string) error {
+	if name == "" {
+		return fmt.Errorf("el nombre del snapshot es obligatorio")
+	}
+	for _, c := range name {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && c != '_' && c != '-' {
+			return fmt.Errorf("el nombre del snapshot solo puede contener letras, números, '_' y '-': %q", name)
+		}
+	}
+	return nil
+}
+
+// Función para obtener las rutas de la imagen y de la información de un snapshot
+func snapshotPaths(name string) (string, string) {
+	return filepath.Join(snapshotsDir, name+".mia"), filepath.Join(snapshotsDir, name+".json")
+}
+
+// Función para copiar un archivo completo, escribiendo primero a un temporal
+// Los bloques llenos de ceros no se escriben, así la copia de un disco disperso también queda dispersa
+func copyFile(src string, dst string) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	tempPath := dst + ".tmp"
+	out, err := os.Create(tempPath)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := copySparse(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return 0, err
+	}
+	return written, os.Rename(tempPath, dst)
+}
+
+// Función para copiar in en out por bloques de 64KB, saltando (con Seek) los bloques llenos de ceros
+// para dejarlos como huecos del archivo. Retorna la cantidad de bytes copiados.
+func copySparse(out *os.File, in io.Reader) (int64, error) {
+	buffer := make([]byte, 64*1024)
+	zeros := make([]byte, len(buffer))
+
+	var written int64
+	for {
+		n, readErr := io.ReadFull(in, buffer)
+		if n > 0 {
+			var err error
+			if bytes.Equal(buffer[:n], zeros[:n]) {
+				_, err = out.Seek(int64(n), io.SeekCurrent)
+			} else {
+				_, err = out.Write(buffer[:n])
+			}
+			if err != nil {
+				return written, err
+			}
+			written += int64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+
+	// Un hueco al final no cambia el tamaño del archivo, por eso se fija con Truncate
+	return written, out.Truncate(written)
+}
+
+// Función para crear un snapshot de un disco con su estado de montaje y sesión
+func CreateSnapshot(path string, name string) (SnapshotInfo, error) {
+	fmt.Println("======INICIO SNAPSHOT======")
+	fmt.Println("Path:", path)
+	fmt.Println("Name:", name)
+
+	if err := validateSnapshotName(name); err != nil {
+		fmt.Println("Error:", err)
+		return SnapshotInfo{}, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		fmt.Println("Error: El disco no existe:", path)
+		return SnapshotInfo{}, fmt.Errorf("el disco %s no existe", path)
+	}
+	diskID, err := generateDiskID(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return SnapshotInfo{}, err
+	}
+
+	imagePath, infoPath := snapshotPaths(name)
+	if _, err := os.Stat(infoPath); err == nil {
+		fmt.Println("Error: Ya existe un snapshot con el nombre:", name)
+		return SnapshotInfo{}, fmt.Errorf("ya existe un snapshot con el nombre %s", name)
+	}
+	if err := os.MkdirAll(snapshotsDir, os.ModePerm); err != nil {
+		fmt.Println("Error al crear la carpeta de snapshots:", err)
+		return SnapshotInfo{}, err
+	}
+
+	// Copiar la imagen del disco
+	size, err := copyFile(path, imagePath)
+	if err != nil {
+		fmt.Println("Error al copiar el disco:", err)
+		return SnapshotInfo{}, fmt.Errorf("no se pudo copiar el disco: %v", err)
+	}
+
+	// Guardar el estado de montaje y sesión del disco
+	info := SnapshotInfo{
+		Name:      name,
+		DiskPath:  path,
+		CreatedAt: time.Now().Format("2006-01-02 15:04:05"),
+		Size:      size,
+	}
+	for _, partition := range mountedPartitions[diskID] {
+		info.Mounts = append(info.Mounts, SnapshotMount{
+			Name:     partition.Name,
+			ID:       partition.ID,
+			LoggedIn: partition.LoggedIn,
+			User:     partition.User,
+			UID:      partition.UID,
+			GID:      partition.GID,
+		})
+	}
+	if diskInfo, ok := diskMountInfos[diskID]; ok {
+		copied := *diskInfo
+		info.Disk = &copied
+	}
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err == nil {
+		err = os.WriteFile(infoPath, data, 0644)
+	}
+	if err != nil {
+		os.Remove(imagePath)
+		fmt.Println("Error al guardar la información del snapshot:", err)
+		return SnapshotInfo{}, err
+	}
+
+	fmt.Printf("Snapshot %s creado: %d bytes, %d particiones montadas\n", name, size, len(info.Mounts))
+	fmt.Println("======FIN SNAPSHOT======")
+	return info, nil
+}
+
+// Función para leer la información de un snapshot
+func readSnapshotInfo(name string) (SnapshotInfo, error) {
+	var info SnapshotInfo
+	_, infoPath := snapshotPaths(name)
+	data, err := os.ReadFile(infoPath)
+	if err != nil {
+		return info, fmt.Errorf("no existe el snapshot %s", name)
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("la información del snapshot %s está dañada: %v", name, err)
+	}
+	return info, nil
+}
+
+// Función para listar los snapshots (de todos los discos si path está vacío), ordenados por fecha
+func ListSnapshots(path string) ([]SnapshotInfo, error) {
+	entries, err := os.ReadDir(snapshotsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer la carpeta de snapshots: %v", err)
+	}
+
+	diskID := ""
+	if path != "" {
+		if diskID, err = generateDiskID(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var snapshots []SnapshotInfo
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		info, err := readSnapshotInfo(name)
+		if err != nil {
+			fmt.Println("Advertencia:", err)
+			continue
+		}
+		// La imagen del snapshot conserva la firma del disco, así que se compara el disco por su ID
+		if path != "" {
+			imagePath, _ := snapshotPaths(name)
+			snapshotDiskID, err := generateDiskID(imagePath)
+			if err != nil || snapshotDiskID != diskID {
+				continue
+			}
+		}
+		snapshots = append(snapshots, info)
+	}
+
+	sort.Slice(snapshots, func(a, b int) bool {
+		return snapshots[a].CreatedAt < snapshots[b].CreatedAt
+	})
+	return snapshots, nil
+}
+
+// Función para imprimir la lista de snapshots
+func PrintSnapshots(path string) {
+	snapshots, err := ListSnapshots(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if len(snapshots) == 0 {
+		fmt.Println("No hay snapshots.")
+		return
+	}
+
+	fmt.Println("Snapshots:")
+	for _, snapshot := range snapshots {
+		var ids []string
+		for _, mount := range snapshot.Mounts {
+			ids = append(ids, mount.ID)
+		}
+		fmt.Printf(" - %s: disco %s, creado %s, %d bytes, montadas: [%s]\n",
+			snapshot.Name, snapshot.DiskPath, snapshot.CreatedAt, snapshot.Size, strings.Join(ids, ", "))
+	}
+}
+
+// Función para regresar un disco al estado de un snapshot. Si el disco tiene particiones montadas
+// solo se permite con force; el estado de montaje y sesión del disco se reemplaza por el del snapshot.
+func Rollback(name string, force bool) error {
+	fmt.Println("======INICIO ROLLBACK======")
+	fmt.Println("Name:", name)
+
+	info, err := readSnapshotInfo(name)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+
+	// El ID del disco sale de la firma guardada en la imagen del snapshot
+	imagePath, _ := snapshotPaths(name)
+	diskID, err := generateDiskID(imagePath)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+	if partitions := mountedPartitions[diskID]; len(partitions) > 0 && !force {
+		var ids []string
+		for _, partition := range partitions {
+			ids = append(ids, partition.ID)
+		}
+		fmt.Println("Error: El disco tiene particiones montadas:", strings.Join(ids, ", "))
+		return fmt.Errorf("el disco tiene particiones montadas (%s), desmóntelas o use -force", strings.Join(ids, ", "))
+	}
+
+	// Restaurar la imagen del disco
+	if _, err := copyFile(imagePath, info.DiskPath); err != nil {
+		fmt.Println("Error al restaurar el disco:", err)
+		return fmt.Errorf("no se pudo restaurar el disco: %v", err)
+	}
+
+	// Reemplazar el estado de montaje del disco por el del snapshot
+	delete(mountedPartitions, diskID)
+	sessionActive := false
+	for _, partitions := range mountedPartitions {
+		for _, partition := range partitions {
+			sessionActive = sessionActive || partition.LoggedIn
+		}
+	}
+
+	// Si la letra del disco ya la tiene otro disco, los IDs del snapshot no se pueden reutilizar
+	letterTaken := info.Disk != nil && isDiskLetterUsed(info.Disk.Letter, diskID)
+	if letterTaken && len(info.Mounts) > 0 {
+		fmt.Printf("Advertencia: La letra %s ya pertenece a otro disco, las particiones del snapshot no se vuelven a montar\n", info.Disk.Letter)
+	}
+
+	for _, mount := range info.Mounts {
+		if letterTaken {
+			break
+		}
+		if isMountedID(mount.ID) {
+			fmt.Printf("Advertencia: El ID %s ya está en uso por otro disco, la partición %s no se vuelve a montar\n", mount.ID, mount.Name)
+			continue
+		}
+		// Solo puede haber una sesión activa a la vez
+		loggedIn := mount.LoggedIn && !sessionActive
+		sessionActive = sessionActive || loggedIn
+		restored := MountedPartition{
+			Path:     info.DiskPath,
+			Name:     mount.Name,
+			ID:       mount.ID,
+			Status:   '1',
+			LoggedIn: loggedIn,
+		}
+		if loggedIn {
+			restored.User, restored.UID, restored.GID = mount.User, mount.UID, mount.GID
+		}
+		mountedPartitions[diskID] = append(mountedPartitions[diskID], restored)
+	}
+
+	// Conservar la letra del disco y no reutilizar correlativos ya entregados
+	if info.Disk != nil {
+		current, ok := diskMountInfos[diskID]
+		if !ok && !letterTaken {
+			restored := *info.Disk
+			restored.Path = info.DiskPath
+			diskMountInfos[diskID] = &restored
+		} else if ok && current.Letter == info.Disk.Letter && info.Disk.Correlative > current.Correlative {
+			current.Correlative = info.Disk.Correlative
+		}
+	}
+	restoreMountInfosFromPartitions()
+	persistMountRegistry()
+
+	fmt.Printf("Disco %s restaurado al snapshot %s (%s)\n", info.DiskPath, name, info.CreatedAt)
+	PrintMountedPartitions()
+	fmt.Println("======FIN ROLLBACK======")
+	return nil
+}
+
+// Función para saber si una letra ya está asignada a un disco distinto de diskID
+func isDiskLetterUsed(letter string, diskID string) bool {
+	for id, info := range diskMountInfos {
+		if id != diskID && info.Letter == letter {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,734 @@
+package DiskManagement
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"proyecto1/Structs"
+	"proyecto1/Utilities"
+)
+
+func TestMain(m *testing.M) {
+	// El registro de montajes y los snapshots se guardan en un directorio temporal y no en el directorio del paquete
+	dir, err := os.MkdirTemp("", "diskmanagement")
+	if err != nil {
+		panic(err)
+	}
+	SetMountRegistryPath(filepath.Join(dir, "mount_registry.json"))
+	SetSnapshotsDir(filepath.Join(dir, "snapshots"))
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// Función para crear un disco de prueba en un directorio temporal
+func newTestDisk(t *testing.T, sizeKB int, fit string, table string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "disk.mia")
+	Mkdisk(sizeKB, fit, "k", path, table, "sparse")
+	return path
+}
+
+// Función para leer la tabla de particiones de un disco de prueba
+func readTestTable(t *testing.T, path string) PartitionTable {
+	t.Helper()
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		t.Fatalf("no se pudo abrir el disco: %v", err)
+	}
+	defer file.Close()
+
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		t.Fatalf("no se pudo leer la tabla de particiones: %v", err)
+	}
+	return table
+}
+
+// Función para buscar una entrada de la tabla por nombre
+func testEntry(t *testing.T, table PartitionTable, name string) PartitionLocation {
+	t.Helper()
+	for _, entry := range table.Entries() {
+		if entry.Name == name {
+			return entry
+		}
+	}
+	t.Fatalf("no se encontró la partición %q", name)
+	return PartitionLocation{}
+}
+
+func TestSelectFreeSpace(t *testing.T) {
+	spaces := []FreeSpace{{Start: 10, Size: 100}, {Start: 200, Size: 50}, {Start: 400, Size: 300}}
+
+	cases := []struct {
+		size  int64
+		fit   byte
+		start int64
+		found bool
+	}{
+		{40, 'f', 10, true},
+		{40, 'b', 200, true},
+		{40, 'w', 400, true},
+		{100, 'b', 10, true},
+		{200, 'f', 400, true},
+		{400, 'w', 0, false},
+	}
+	for _, c := range cases {
+		space, found := SelectFreeSpace(spaces, c.size, c.fit)
+		if found != c.found || space.Start != c.start {
+			t.Errorf("SelectFreeSpace(%d, %c) = %+v, %v; se esperaba inicio %d, %v", c.size, c.fit, space, found, c.start, c.found)
+		}
+	}
+}
+
+func TestMkdiskWritesMBR(t *testing.T) {
+	path := newTestDisk(t, 64, "bf", "mbr")
+
+	table := readTestTable(t, path)
+	if table.Kind() != "mbr" || table.DiskSize() != 64*1024 || table.DiskFit() != 'b' {
+		t.Fatalf("tabla inesperada: %s, %d bytes, ajuste %c", table.Kind(), table.DiskSize(), table.DiskFit())
+	}
+	if len(table.Entries()) != 0 {
+		t.Fatalf("un disco nuevo no debe tener particiones: %+v", table.Entries())
+	}
+}
+
+func TestFdiskFitPlacement(t *testing.T) {
+	for _, c := range []struct {
+		fit      string
+		expected string // Partición que debe quedar justo antes de la nueva
+	}{
+		{"b", "a"}, // El hueco de 100k entre a y c es el más pequeño donde cabe
+		{"f", "a"}, // También es el primero
+		{"w", "c"}, // El espacio al final del disco es el más grande
+	} {
+		path := newTestDisk(t, 1024, "ff", "mbr")
+		for _, name := range []string{"a", "b", "c"} {
+			if err := Fdisk(100, path, name, "k", "p", ""); err != nil {
+				t.Fatalf("fdisk %s: %v", name, err)
+			}
+		}
+		DeletePartition(path, "b", "fast")
+
+		if err := Fdisk(50, path, "d", "k", "p", c.fit); err != nil {
+			t.Fatalf("fdisk con ajuste %s: %v", c.fit, err)
+		}
+
+		table := readTestTable(t, path)
+		before := testEntry(t, table, c.expected)
+		if created := testEntry(t, table, "d"); created.Start != before.Start+before.Size {
+			t.Errorf("ajuste %s: la partición inicia en %d, se esperaba %d", c.fit, created.Start, before.Start+before.Size)
+		}
+	}
+}
+
+func TestFdiskRejectsPartitionWithoutSpace(t *testing.T) {
+	path := newTestDisk(t, 64, "ff", "mbr")
+	if err := Fdisk(60, path, "a", "k", "p", ""); err != nil {
+		t.Fatalf("fdisk: %v", err)
+	}
+	if err := Fdisk(10, path, "b", "k", "p", ""); err == nil {
+		t.Fatal("se esperaba un error por falta de espacio")
+	}
+}
+
+// Función para leer la cadena de EBRs de la partición extendida de un disco de prueba
+func readTestChain(t *testing.T, path string) []LogicalPartition {
+	t.Helper()
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		t.Fatalf("no se pudo abrir el disco: %v", err)
+	}
+	defer file.Close()
+
+	table, err := ReadPartitionTable(file)
+	if err != nil {
+		t.Fatalf("no se pudo leer la tabla de particiones: %v", err)
+	}
+	extended, ok := table.Extended()
+	if !ok {
+		t.Fatal("el disco no tiene partición extendida")
+	}
+	chain, err := ReadEBRChain(file, extended)
+	if err != nil {
+		t.Fatalf("no se pudo leer la cadena de EBRs: %v", err)
+	}
+	return chain
+}
+
+// Función para obtener los nombres de las lógicas de la cadena en orden ("" para la cabecera vacía)
+func chainNames(chain []LogicalPartition) []string {
+	var names []string
+	for _, logical := range chain {
+		names = append(names, strings.TrimRight(string(logical.EBR.PartName[:]), "\x00"))
+	}
+	return names
+}
+
+// Función para crear un disco con una partición extendida y las lógicas indicadas (100k cada una)
+func newExtendedTestDisk(t *testing.T, logicals ...string) string {
+	t.Helper()
+	path := newTestDisk(t, 1024, "ff", "mbr")
+	if err := Fdisk(500, path, "ext", "k", "e", ""); err != nil {
+		t.Fatalf("fdisk extendida: %v", err)
+	}
+	for _, name := range logicals {
+		if err := Fdisk(100, path, name, "k", "l", ""); err != nil {
+			t.Fatalf("fdisk lógica %s: %v", name, err)
+		}
+	}
+	return path
+}
+
+func TestLogicalPartitionsStayOrderedAndInBounds(t *testing.T) {
+	path := newExtendedTestDisk(t, "l1", "l2", "l3")
+	extended, _ := readTestTable(t, path).Extended()
+
+	chain := readTestChain(t, path)
+	if got := strings.Join(chainNames(chain), ","); got != "l1,l2,l3" {
+		t.Fatalf("cadena inesperada: %s", got)
+	}
+	ebrSize := int64(binary.Size(Structs.EBR{}))
+	for i, logical := range chain {
+		if logical.EBR.PartStart != logical.Position+ebrSize {
+			t.Errorf("%d: los datos deben ir justo después del EBR", i)
+		}
+		if logical.EBR.PartStart+logical.EBR.PartSize > extended.Start+extended.Size {
+			t.Errorf("%d: la lógica sale de la partición extendida", i)
+		}
+		if i > 0 && logical.Position < chain[i-1].EBR.PartStart+chain[i-1].EBR.PartSize {
+			t.Errorf("%d: la lógica se solapa con la anterior", i)
+		}
+	}
+}
+
+func TestDeleteLogicalKeepsChainLinked(t *testing.T) {
+	path := newExtendedTestDisk(t, "l1", "l2", "l3")
+	hole := readTestChain(t, path)[1].Position
+
+	// Eliminar una lógica intermedia enlaza la anterior con la siguiente
+	DeletePartition(path, "l2", "full")
+	if got := strings.Join(chainNames(readTestChain(t, path)), ","); got != "l1,l3" {
+		t.Fatalf("cadena después de eliminar l2: %s", got)
+	}
+
+	// Con mejor ajuste la nueva lógica ocupa el hueco que dejó l2 y queda en orden
+	if err := Fdisk(50, path, "l4", "k", "l", "b"); err != nil {
+		t.Fatalf("fdisk l4: %v", err)
+	}
+	chain := readTestChain(t, path)
+	if got := strings.Join(chainNames(chain), ","); got != "l1,l4,l3" {
+		t.Fatalf("cadena después de crear l4: %s", got)
+	}
+	if chain[1].Position != hole {
+		t.Errorf("l4 inicia en %d, se esperaba el hueco de l2 en %d", chain[1].Position, hole)
+	}
+
+	// Eliminar la primera lógica deja la cabecera vacía sin perder el resto de la cadena
+	DeletePartition(path, "l1", "fast")
+	chain = readTestChain(t, path)
+	if got := strings.Join(chainNames(chain), ","); got != ",l4,l3" || chain[0].EBR.PartSize != 0 {
+		t.Fatalf("cadena después de eliminar l1: %q", got)
+	}
+
+	// La cabecera vacía se reutiliza para la siguiente lógica que quepa al inicio
+	if err := Fdisk(80, path, "l5", "k", "l", "f"); err != nil {
+		t.Fatalf("fdisk l5: %v", err)
+	}
+	if got := strings.Join(chainNames(readTestChain(t, path)), ","); got != "l5,l4,l3" {
+		t.Fatalf("cadena después de crear l5: %s", got)
+	}
+}
+
+func TestLogicalPartitionNeedsSpaceForEBR(t *testing.T) {
+	path := newExtendedTestDisk(t, "l1", "l2", "l3", "l4")
+
+	// Quedan 100k libres en la extendida, pero una lógica de 100k también necesita su EBR
+	if err := Fdisk(100, path, "l5", "k", "l", ""); err == nil {
+		t.Fatal("se esperaba un error por falta de espacio para el EBR")
+	}
+	if err := Fdisk(99, path, "l5", "k", "l", ""); err != nil {
+		t.Fatalf("fdisk l5: %v", err)
+	}
+}
+
+// Función para descomprimir un disco de testdata en un directorio temporal
+// Los discos de testdata se crearon con los comandos de versiones anteriores del proyecto
+func extractTestDisk(t *testing.T, name string) string {
+	t.Helper()
+	compressed, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("no se pudo leer %s: %v", name, err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("no se pudo descomprimir %s: %v", name, err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("no se pudo descomprimir %s: %v", name, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "legacy.mia")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("no se pudo escribir el disco: %v", err)
+	}
+	return path
+}
+
+// Función para leer users.txt (inodo 1) de la partición formateada que empieza en start
+func readTestUsersFile(t *testing.T, path string, start int64) string {
+	t.Helper()
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		t.Fatalf("no se pudo abrir el disco: %v", err)
+	}
+	defer file.Close()
+
+	var superblock Structs.Superblock
+	if err := Utilities.ReadObject(file, &superblock, start); err != nil || superblock.S_magic != 0xEF53 {
+		t.Fatalf("no hay un Superblock válido en %d", start)
+	}
+	var inode Structs.Inode
+	if err := Utilities.ReadObject(file, &inode, superblock.S_inode_start+int64(superblock.S_inode_size)); err != nil {
+		t.Fatalf("no se pudo leer el inodo de users.txt: %v", err)
+	}
+	var block Structs.Fileblock
+	if err := Utilities.ReadObject(file, &block, superblock.S_block_start+int64(inode.I_block[0])*int64(superblock.S_block_size)); err != nil {
+		t.Fatalf("no se pudo leer el bloque de users.txt: %v", err)
+	}
+	return string(block.B_content[:min(int(inode.I_size), len(block.B_content))])
+}
+
+func TestMigrateBaselineDisk(t *testing.T) {
+	// Disco creado con mkdisk, fdisk (p1, ext con l1 y l2, p2), mount y mkfs -fs=2fs del formato anterior (versión 1)
+	path := extractTestDisk(t, "v1.mia.gz")
+
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadPartitionTable(file)
+	file.Close()
+	if err == nil || !strings.Contains(err.Error(), "migrate") {
+		t.Fatalf("un disco sin migrar debe pedir el comando migrate, se obtuvo: %v", err)
+	}
+
+	if err := MigrateDisk(path); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	table := readTestTable(t, path)
+	p1 := testEntry(t, table, "p1")
+	testEntry(t, table, "ext")
+	p2 := testEntry(t, table, "p2")
+	if p1.Start < table.FirstUsable() || p2.Size != 100*1024 {
+		t.Fatalf("particiones inesperadas: p1 %+v, p2 %+v", p1, p2)
+	}
+	if p1.Id != "341a" {
+		t.Errorf("el ID de montaje de p1 es %q", p1.Id)
+	}
+	if got := strings.Join(chainNames(readTestChain(t, path)), ","); got != "l1,l2" {
+		t.Fatalf("cadena de EBRs migrada: %s", got)
+	}
+	for _, logical := range readTestChain(t, path) {
+		if logical.EBR.PartSize != 100*1024 {
+			t.Errorf("la lógica %s cambió de tamaño: %d", chainNames([]LogicalPartition{logical})[0], logical.EBR.PartSize)
+		}
+	}
+	if users := readTestUsersFile(t, path, p1.Start); users != "1,G,root\n1,U,root,root,123\n" {
+		t.Fatalf("users.txt migrado: %q", users)
+	}
+
+	// Migrar otra vez no cambia nada
+	if err := MigrateDisk(path); err != nil {
+		t.Fatalf("migrate sobre un disco ya migrado: %v", err)
+	}
+}
+
+func TestMigrateRefusesInconsistentDisk(t *testing.T) {
+	path := extractTestDisk(t, "v1.mia.gz")
+
+	// Hacer que p2 (tercera entrada del MBR de 159 bytes) se solape con p1
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entryStart := int64(19 + 2*35 + 3)
+	if err := Utilities.WriteObject(file, int32(200), entryStart); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	before, _ := os.ReadFile(path)
+	if err := MigrateDisk(path); err == nil {
+		t.Fatal("se esperaba que migrate rechazara un disco con particiones solapadas")
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Fatal("migrate modificó un disco que rechazó")
+	}
+}
+
+// Función para escribir una marca en una posición del disco de prueba
+func writeTestMark(t *testing.T, path string, position int64, mark string) {
+	t.Helper()
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteAt([]byte(mark), position); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Función para leer len(mark) bytes de una posición del disco de prueba y compararlos con la marca
+func hasTestMark(t *testing.T, path string, position int64, mark string) bool {
+	t.Helper()
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	data := make([]byte, len(mark))
+	if _, err := file.ReadAt(data, position); err != nil {
+		t.Fatal(err)
+	}
+	return string(data) == mark
+}
+
+func TestCompactDiskMovesPartitions(t *testing.T) {
+	path := newTestDisk(t, 1024, "ff", "mbr")
+	for _, name := range []string{"a", "b", "c"} {
+		if err := Fdisk(100, path, name, "k", "p", ""); err != nil {
+			t.Fatalf("fdisk %s: %v", name, err)
+		}
+	}
+	table := readTestTable(t, path)
+	hole := testEntry(t, table, "b").Start
+	writeTestMark(t, path, testEntry(t, table, "c").Start, "datos de c")
+	DeletePartition(path, "b", "fast")
+
+	if err := CompactDisk(path); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	c := testEntry(t, readTestTable(t, path), "c")
+	if c.Start != hole {
+		t.Fatalf("c inicia en %d, se esperaba %d", c.Start, hole)
+	}
+	if !hasTestMark(t, path, c.Start, "datos de c") {
+		t.Fatal("los datos de c no se movieron con la partición")
+	}
+}
+
+func TestCompactDiskRebasesEBRChain(t *testing.T) {
+	path := newExtendedTestDisk(t, "l1", "l2", "l3")
+	chain := readTestChain(t, path)
+	writeTestMark(t, path, chain[2].EBR.PartStart, "datos de l3")
+	DeletePartition(path, "l2", "fast")
+
+	if err := CompactDisk(path); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	chain = readTestChain(t, path)
+	if got := strings.Join(chainNames(chain), ","); got != "l1,l3" {
+		t.Fatalf("cadena después de compactar: %s", got)
+	}
+	// l3 queda justo después de l1 y el EBR de l1 apunta a su nueva posición
+	if chain[1].Position != chain[0].EBR.PartStart+chain[0].EBR.PartSize || chain[0].EBR.PartNext != chain[1].Position {
+		t.Fatalf("l3 no quedó enlazada justo después de l1: %+v", chain)
+	}
+	if !hasTestMark(t, path, chain[1].EBR.PartStart, "datos de l3") {
+		t.Fatal("los datos de l3 no se movieron con la lógica")
+	}
+}
+
+func TestCompactDiskRefusesMountedDisk(t *testing.T) {
+	CleanMountedPartitions()
+	defer CleanMountedPartitions()
+
+	path := newTestDisk(t, 1024, "ff", "mbr")
+	for _, name := range []string{"a", "b", "c"} {
+		if err := Fdisk(100, path, name, "k", "p", ""); err != nil {
+			t.Fatalf("fdisk %s: %v", name, err)
+		}
+	}
+	DeletePartition(path, "b", "fast")
+	Mount(path, "c")
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CompactDisk(path); err == nil {
+		t.Fatal("se esperaba un error al compactar un disco con particiones montadas")
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatal("el disco cambió aunque la compactación se rechazó")
+	}
+}
+
+// Función para escribir un EBR en una posición del disco de prueba
+func writeTestEBR(t *testing.T, path string, ebr Structs.EBR, position int64) {
+	t.Helper()
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := Utilities.WriteObject(file, ebr, position); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckDiskReportsWithoutRepair(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk.mia")
+	if err := CreateStaleMountDisk(path, "991a"); err != nil {
+		t.Fatalf("no se pudo crear el disco: %v", err)
+	}
+
+	issues, err := CheckDisk(path, false)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Repaired {
+		t.Fatalf("problemas inesperados: %+v", issues)
+	}
+	if entry := testEntry(t, readTestTable(t, path), "a"); entry.Status != '1' {
+		t.Fatal("check sin repair no debe modificar el disco")
+	}
+}
+
+func TestCheckDiskRepairsStaleMount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk.mia")
+	if err := CreateStaleMountDisk(path, "991a"); err != nil {
+		t.Fatalf("no se pudo crear el disco: %v", err)
+	}
+
+	issues, err := CheckDisk(path, true)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(issues) != 1 || !issues[0].Repaired {
+		t.Fatalf("problemas inesperados: %+v", issues)
+	}
+	if entry := testEntry(t, readTestTable(t, path), "a"); entry.Status == '1' || entry.Id != "" {
+		t.Fatalf("la marca de montaje no se limpió: %c %q", entry.Status, entry.Id)
+	}
+	if issues, _ := CheckDisk(path, false); len(issues) != 0 {
+		t.Fatalf("el disco reparado todavía tiene problemas: %+v", issues)
+	}
+}
+
+func TestCheckDiskCutsEBRCycle(t *testing.T) {
+	path := newExtendedTestDisk(t, "l1", "l2", "l3")
+	chain := readTestChain(t, path)
+	last := chain[2]
+	last.EBR.PartNext = chain[0].Position
+	writeTestEBR(t, path, last.EBR, last.Position)
+
+	issues, err := CheckDisk(path, true)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(issues) != 1 || !issues[0].Repaired || !strings.Contains(issues[0].Problem, "ciclo") {
+		t.Fatalf("problemas inesperados: %+v", issues)
+	}
+	if got := strings.Join(chainNames(readTestChain(t, path)), ","); got != "l1,l2,l3" {
+		t.Fatalf("cadena después de reparar: %s", got)
+	}
+}
+
+func TestCheckDiskRelinksChainInOrder(t *testing.T) {
+	path := newExtendedTestDisk(t, "l1", "l2", "l3")
+	chain := readTestChain(t, path)
+
+	// Enlazar l1 -> l3 -> l2
+	first, second, third := chain[0], chain[1], chain[2]
+	first.EBR.PartNext = third.Position
+	third.EBR.PartNext = second.Position
+	second.EBR.PartNext = -1
+	for _, logical := range []LogicalPartition{first, second, third} {
+		writeTestEBR(t, path, logical.EBR, logical.Position)
+	}
+
+	issues, err := CheckDisk(path, false)
+	if err != nil || len(issues) != 1 || issues[0].Repaired {
+		t.Fatalf("problemas inesperados: %+v (%v)", issues, err)
+	}
+	if issues, err = CheckDisk(path, true); err != nil || len(issues) != 1 || !issues[0].Repaired {
+		t.Fatalf("problemas inesperados: %+v (%v)", issues, err)
+	}
+	if got := strings.Join(chainNames(readTestChain(t, path)), ","); got != "l1,l2,l3" {
+		t.Fatalf("cadena después de reparar: %s", got)
+	}
+}
+
+func TestCheckDiskTrimsPartitionPastDiskEnd(t *testing.T) {
+	path := newTestDisk(t, 1024, "ff", "mbr")
+	if err := Fdisk(100, path, "a", "k", "p", ""); err != nil {
+		t.Fatalf("fdisk: %v", err)
+	}
+	table := readTestTable(t, path)
+	entry := testEntry(t, table, "a")
+	entry.Size = table.DiskSize()
+	table.Update(entry)
+	file, err := Utilities.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Write(file); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	issues, err := CheckDisk(path, true)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(issues) != 1 || !issues[0].Repaired {
+		t.Fatalf("problemas inesperados: %+v", issues)
+	}
+	table = readTestTable(t, path)
+	if entry = testEntry(t, table, "a"); entry.Start+entry.Size != table.LastUsable() {
+		t.Fatalf("la partición termina en %d, se esperaba %d", entry.Start+entry.Size, table.LastUsable())
+	}
+}
+
+func TestLoadPartitionRefusesImageOutsidePartition(t *testing.T) {
+	path := newTestDisk(t, 1024, "ff", "mbr")
+	if err := Fdisk(100, path, "a", "k", "p", ""); err != nil {
+		t.Fatalf("fdisk: %v", err)
+	}
+
+	// Imagen con las posiciones absolutas de un Superblock que estaba en otra parte de un disco
+	superblock := Structs.Superblock{S_magic: 0xEF53, S_inodes_count: 10, S_blocks_count: 30, S_block_size: 64}
+	superblock.S_bm_inode_start = 500 * 1024
+	superblock.S_bm_block_start = superblock.S_bm_inode_start + 10
+	superblock.S_inode_start = superblock.S_bm_block_start + 30
+	superblock.S_block_start = superblock.S_inode_start + 10*int64(binary.Size(Structs.Inode{}))
+	var image bytes.Buffer
+	if err := binary.Write(&image, binary.LittleEndian, superblock); err != nil {
+		t.Fatal(err)
+	}
+	imagePath := filepath.Join(t.TempDir(), "image.bin")
+	if err := os.WriteFile(imagePath, image.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadPartition(path, "a", imagePath); err == nil {
+		t.Fatal("se esperaba un error por una imagen con posiciones fuera de la partición")
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatal("el disco cambió aunque la imagen se rechazó")
+	}
+}
+
+func TestSnapshotRollbackRestoresDisk(t *testing.T) {
+	CleanMountedPartitions()
+	path := newTestDisk(t, 4096, "ff", "mbr")
+	if err := Fdisk(1024, path, "a", "k", "p", ""); err != nil {
+		t.Fatalf("fdisk: %v", err)
+	}
+	start := testEntry(t, readTestTable(t, path), "a").Start
+	writeTestMark(t, path, start+300*1024, "antes")
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CreateSnapshot(path, "antes"); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	imagePath, _ := snapshotPaths("antes")
+	if image, err := os.ReadFile(imagePath); err != nil || !bytes.Equal(image, original) {
+		t.Fatalf("la imagen del snapshot no es igual al disco (%v)", err)
+	}
+
+	writeTestMark(t, path, start+300*1024, "despues")
+	DeletePartition(path, "a", "fast")
+	if err := Rollback("antes", false); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if restored, err := os.ReadFile(path); err != nil || !bytes.Equal(restored, original) {
+		t.Fatalf("el disco restaurado no es igual al original (%v)", err)
+	}
+}
+
+func TestRecoverDeletedExtendedPartition(t *testing.T) {
+	path := newExtendedTestDisk(t, "l1", "l2")
+	extended, _ := readTestTable(t, path).Extended()
+	DeletePartition(path, "ext", "fast")
+	if _, ok := readTestTable(t, path).Extended(); ok {
+		t.Fatal("la extendida no se eliminó")
+	}
+
+	// Sin confirm solo se proponen las particiones encontradas
+	proposals, err := RecoverPartitions(path, false)
+	if err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+	if len(proposals) != 1 || proposals[0].Type != "e" || proposals[0].Start != extended.Start || proposals[0].Logicals != 2 {
+		t.Fatalf("propuestas inesperadas: %+v", proposals)
+	}
+	if _, ok := readTestTable(t, path).Extended(); ok {
+		t.Fatal("recover sin confirm modificó la tabla")
+	}
+
+	if _, err := RecoverPartitions(path, true); err != nil {
+		t.Fatalf("recover -confirm: %v", err)
+	}
+	if got := strings.Join(chainNames(readTestChain(t, path)), ","); got != "l1,l2" {
+		t.Fatalf("cadena recuperada: %s", got)
+	}
+	if issues, err := CheckDisk(path, false); err != nil || len(issues) != 0 {
+		t.Fatalf("el disco recuperado tiene problemas: %+v (%v)", issues, err)
+	}
+}
+
+func TestRecoverIgnoresPartitionsInTable(t *testing.T) {
+	path := newExtendedTestDisk(t, "l1")
+	if proposals, err := RecoverPartitions(path, false); err != nil || len(proposals) != 0 {
+		t.Fatalf("no se esperaban propuestas: %+v (%v)", proposals, err)
+	}
+}
+
+func TestCopyPartitionFailureLeavesNoPartition(t *testing.T) {
+	source := newTestDisk(t, 1024, "ff", "mbr")
+	if err := Fdisk(100, source, "a", "k", "p", ""); err != nil {
+		t.Fatalf("fdisk: %v", err)
+	}
+	// Cortar el disco origen para que los datos de la partición no se puedan leer completos
+	if err := os.Truncate(source, testEntry(t, readTestTable(t, source), "a").Start+10*1024); err != nil {
+		t.Fatal(err)
+	}
+
+	destination := newExtendedTestDisk(t, "l1")
+	if err := CopyPartition(source, "a", destination, "copia", "l"); err == nil {
+		t.Fatal("se esperaba un error al copiar una partición que no se puede leer")
+	}
+	if got := strings.Join(chainNames(readTestChain(t, destination)), ","); got != "l1" {
+		t.Fatalf("la copia fallida dejó la cadena %s", got)
+	}
+	if issues, err := CheckDisk(destination, false); err != nil || len(issues) != 0 {
+		t.Fatalf("el disco destino quedó con problemas: %+v (%v)", issues, err)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package DiskManagement
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+// Función para obtener los bytes que un archivo ocupa realmente en el disco del host
+func allocatedBytes(t *testing.T, path string) int64 {
+	t.Helper()
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stat.Sys().(*syscall.Stat_t).Blocks * 512
+}
+
+func TestSnapshotKeepsDiskSparse(t *testing.T) {
+	CleanMountedPartitions()
+	path := newTestDisk(t, 8192, "ff", "mbr")
+	if err := Fdisk(1024, path, "a", "k", "p", ""); err != nil {
+		t.Fatalf("fdisk: %v", err)
+	}
+	writeTestMark(t, path, 6*1024*1024, "datos")
+	if allocatedBytes(t, path) > 1024*1024 {
+		t.Skip("el sistema de archivos del host no admite archivos dispersos")
+	}
+
+	info, err := CreateSnapshot(path, "disperso")
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	imagePath, _ := snapshotPaths("disperso")
+	if info.Size != 8192*1024 {
+		t.Fatalf("la imagen tiene %d bytes, se esperaban %d", info.Size, 8192*1024)
+	}
+	if stat, err := os.Stat(imagePath); err != nil || stat.Size() != info.Size {
+		t.Fatalf("la imagen no conserva el tamaño del disco (%v)", err)
+	}
+	if allocated := allocatedBytes(t, imagePath); allocated > 1024*1024 {
+		t.Fatalf("la imagen ocupa %d bytes en el host, la copia no conservó los huecos", allocated)
+	}
+	if !hasTestMark(t, imagePath, 6*1024*1024, "datos") {
+		t.Fatal("la imagen no tiene los datos del disco")
+	}
+}
@@ -1,13 +1,40 @@
 package FileSystem
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"os"
 	"proyecto1/DiskManagement"
 	"proyecto1/Structs"
 	"proyecto1/Utilities"
 	"strings"
+	"time"
+)
+
+// Tipos de inodo (I_type)
+const (
+	inodeFolder byte = '0'
+	inodeFile   byte = '1'
+)
+
+// Cantidad de apuntadores directos en I_block
+const directBlocks = 12
+
+// Cantidad de apuntadores de un Pointerblock
+const pointersPerBlock = 16
+
+// Cantidad máxima de bloques de un inodo: directos, indirecto simple, doble y triple
+const maxInodeBlocks = directBlocks + pointersPerBlock + pointersPerBlock*pointersPerBlock + pointersPerBlock*pointersPerBlock*pointersPerBlock
+
+// Largo máximo de un nombre dentro de un Folderblock (B_name)
+const maxNameLength = 12
+
+// Permisos de I_perm (se suman en cada dígito)
+const (
+	permissionRead  byte = 4
+	permissionWrite byte = 2
 )
 
 func Mkfs(id string, type_ string, fs_ string) {
@@ -17,21 +44,7 @@ func Mkfs(id string, type_ string, fs_ string) {
 	fmt.Println("Fs:", fs_)
 
 	// Buscar la partición montada por ID
-	var mountedPartition DiskManagement.MountedPartition
-	var partitionFound bool
-
-	for _, partitions := range DiskManagement.GetMountedPartitions() {
-		for _, partition := range partitions {
-			if partition.ID == id {
-				mountedPartition = partition
-				partitionFound = true
-				break
-			}
-		}
-		if partitionFound {
-			break
-		}
-	}
+	mountedPartition, partitionFound := DiskManagement.GetMountedPartition(id)
 
 	if !partitionFound {
 		fmt.Println("Particion no encontrada")
@@ -48,94 +61,90 @@ func Mkfs(id string, type_ string, fs_ string) {
 	if err != nil {
 		return
 	}
+	defer file.Close()
 
-	var TempMBR Structs.MRB
-	// Leer objeto desde archivo binario
-	if err := Utilities.ReadObject(file, &TempMBR, 0); err != nil {
-		return
-	}
-
-	// Imprimir objeto
-	Structs.PrintMBR(TempMBR)
-
-	fmt.Println("-------------")
-
-	var index int = -1
-	// Iterar sobre las particiones para encontrar la que tiene el nombre correspondiente
-	for i := 0; i < 4; i++ {
-		if TempMBR.Partitions[i].Size != 0 {
-			if strings.Contains(string(TempMBR.Partitions[i].Id[:]), id) {
-				index = i
-				break
-			}
-		}
-	}
-
-	if index != -1 {
-		Structs.PrintPartition(TempMBR.Partitions[index])
-	} else {
-		fmt.Println("Particion no encontrada (2)")
+	// Buscar la partición (primaria o lógica) con el ID dentro del disco
+	partition, err := DiskManagement.FindPartitionByID(file, id)
+	if err != nil {
+		fmt.Println("Particion no encontrada (2):", err)
 		return
 	}
+	fmt.Printf("Partición: %s, tipo: %c, inicio: %d, tamaño: %d\n", partition.Name, partition.Type, partition.Start, partition.Size)
 
-	// Calcular el número de inodos y bloques
-	numerador := int32(TempMBR.Partitions[index].Size - int32(binary.Size(Structs.Superblock{})))
-	denominador_base := int32(4 + int32(binary.Size(Structs.Inode{})) + 3*int32(binary.Size(Structs.Fileblock{})))
-	var temp int32 = 0
-
-	// Agregar journaling si es EXT3
-	if fs_ == "3fs" {
-		temp = int32(binary.Size(Structs.Journaling{})) // Añadir journaling para EXT3
-	} else if fs_ == "2fs" {
-		temp = 0 // Sin journaling para EXT2
+	// Crear el Superblock con todos los campos calculados
+	var newSuperblock Structs.Superblock
+	if fs_ == "2fs" {
+		newSuperblock.S_filesystem_type = 2 // EXT2
+	} else if fs_ == "3fs" {
+		newSuperblock.S_filesystem_type = 3 // EXT3
 	} else {
 		fmt.Println("Error: Sólo están disponibles los sistemas de archivos 2FS y 3FS.")
 		return
 	}
 
-	denominador := denominador_base + temp
-	n := int32(numerador / denominador)
+	// Calcular el número de inodos y bloques, y las posiciones de inicio
+	CalculateLayout(&newSuperblock, partition.Start, partition.Size, newSuperblock.S_filesystem_type == 3)
+	n := newSuperblock.S_inodes_count
 
 	fmt.Println("INODOS:", n)
 
-	// Crear el Superblock con todos los campos calculados
-	var newSuperblock Structs.Superblock
-	if fs_ == "2fs" {
-		newSuperblock.S_filesystem_type = 2 // EXT2
-	} else if fs_ == "3fs" {
-		newSuperblock.S_filesystem_type = 3 // EXT3
-	}
-	newSuperblock.S_inodes_count = n
-	newSuperblock.S_blocks_count = 3 * n
 	newSuperblock.S_free_blocks_count = 3*n - 2
 	newSuperblock.S_free_inodes_count = n - 2
+	newSuperblock.S_fist_ino = 2  // Los inodos 0 (raíz) y 1 (users.txt) ya están usados
+	newSuperblock.S_first_blo = 2 // Los bloques 0 y 1 también
 	copy(newSuperblock.S_mtime[:], "23/08/2024")
 	copy(newSuperblock.S_umtime[:], "23/08/2024")
 	newSuperblock.S_mnt_count = 1
 	newSuperblock.S_magic = 0xEF53
-	newSuperblock.S_inode_size = int32(binary.Size(Structs.Inode{}))
-	newSuperblock.S_block_size = int32(binary.Size(Structs.Fileblock{}))
-
-	// Calcula las posiciones de inicio
-	newSuperblock.S_bm_inode_start = TempMBR.Partitions[index].Start + int32(binary.Size(Structs.Superblock{}))
-	newSuperblock.S_bm_block_start = newSuperblock.S_bm_inode_start + n
-	newSuperblock.S_inode_start = newSuperblock.S_bm_block_start + 3*n
-	newSuperblock.S_block_start = newSuperblock.S_inode_start + n*newSuperblock.S_inode_size
 
 	// Llamar a la función correspondiente para crear el sistema de archivos
 	if fs_ == "2fs" {
-		create_ext2(n, TempMBR.Partitions[index], newSuperblock, "23/08/2024", file)
+		create_ext2(n, partition.Start, newSuperblock, "23/08/2024", file)
 	} else if fs_ == "3fs" {
-		create_ext3(n, TempMBR.Partitions[index], newSuperblock, "23/08/2024", file)
+		create_ext3(n, partition.Start, newSuperblock, "23/08/2024", file)
 	}
 
-	// Cerrar archivo binario
-	defer file.Close()
-
 	fmt.Println("======FIN MKFS======")
 }
 
-func create_ext2(n int32, partition Structs.Partition, newSuperblock Structs.Superblock, date string, file *os.File) {
+// Función para calcular el número de inodos (n) y el inicio de cada área del sistema de archivos
+// según el tamaño de la partición. Con journaled, el área del journaling (una entrada por inodo) va justo
+// después del Superblock; los EXT3 creados antes de que existiera esa área no la tienen (ver journalingEntries)
+// y conservan los bitmaps justo después del Superblock.
+func CalculateLayout(superblock *Structs.Superblock, partitionStart int64, partitionSize int64, journaled bool) {
+	superblockSize := int64(binary.Size(Structs.Superblock{}))
+	inodeSize := int64(binary.Size(Structs.Inode{}))
+	blockSize := int64(binary.Size(Structs.Fileblock{}))
+
+	var journalingSize int64 = 0
+	if superblock.S_filesystem_type == 3 {
+		journalingSize = int64(binary.Size(Structs.Journaling{}))
+	}
+
+	numerador := partitionSize - superblockSize
+	denominador := 4 + inodeSize + 3*blockSize + journalingSize
+	n := numerador / denominador
+
+	// Los contadores y apuntadores de bloques son de 32 bits, 3*n no puede desbordarse
+	if n > math.MaxInt32/3 {
+		n = math.MaxInt32 / 3
+	}
+
+	superblock.S_inodes_count = int32(n)
+	superblock.S_blocks_count = int32(3 * n)
+	superblock.S_inode_size = int32(inodeSize)
+	superblock.S_block_size = int32(blockSize)
+
+	superblock.S_bm_inode_start = partitionStart + superblockSize
+	if journaled {
+		superblock.S_bm_inode_start += n * journalingSize
+	}
+	superblock.S_bm_block_start = superblock.S_bm_inode_start + n
+	superblock.S_inode_start = superblock.S_bm_block_start + 3*n
+	superblock.S_block_start = superblock.S_inode_start + n*inodeSize
+}
+
+func create_ext2(n int32, partitionStart int64, newSuperblock Structs.Superblock, date string, file *os.File) {
 	fmt.Println("======Start CREATE EXT2======")
 	fmt.Println("INODOS:", n)
 
@@ -145,14 +154,14 @@ func create_ext2(n int32, partition Structs.Partition, newSuperblock Structs.Sup
 
 	// Escribe los bitmaps de inodos y bloques en el archivo
 	for i := int32(0); i < n; i++ {
-		if err := Utilities.WriteObject(file, byte(0), int64(newSuperblock.S_bm_inode_start+i)); err != nil {
+		if err := Utilities.WriteObject(file, byte(0), newSuperblock.S_bm_inode_start+int64(i)); err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
 	}
 
 	for i := int32(0); i < 3*n; i++ {
-		if err := Utilities.WriteObject(file, byte(0), int64(newSuperblock.S_bm_block_start+i)); err != nil {
+		if err := Utilities.WriteObject(file, byte(0), newSuperblock.S_bm_block_start+int64(i)); err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
@@ -171,7 +180,7 @@ func create_ext2(n int32, partition Structs.Partition, newSuperblock Structs.Sup
 	}
 
 	// Escribe el superbloque actualizado al archivo
-	if err := Utilities.WriteObject(file, newSuperblock, int64(partition.Start)); err != nil {
+	if err := Utilities.WriteObject(file, newSuperblock, partitionStart); err != nil {
 		fmt.Println("Error: ", err)
 		return
 	}
@@ -185,7 +194,7 @@ func create_ext2(n int32, partition Structs.Partition, newSuperblock Structs.Sup
 	// Imprimir Fileblocks
 	for i := int32(0); i < 1; i++ {
 		var fileblock Structs.Fileblock
-		offset := int64(newSuperblock.S_block_start + int32(binary.Size(Structs.Folderblock{})) + i*int32(binary.Size(Structs.Fileblock{})))
+		offset := newSuperblock.S_block_start + int64(binary.Size(Structs.Folderblock{})) + int64(i)*int64(binary.Size(Structs.Fileblock{}))
 		if err := Utilities.ReadObject(file, &fileblock, offset); err != nil {
 			fmt.Println("Error al leer Fileblock: ", err)
 			return
@@ -201,7 +210,7 @@ func create_ext2(n int32, partition Structs.Partition, newSuperblock Structs.Sup
 	fmt.Println("======End CREATE EXT2======")
 }
 
-func create_ext3(n int32, partition Structs.Partition, newSuperblock Structs.Superblock, date string, file *os.File) {
+func create_ext3(n int32, partitionStart int64, newSuperblock Structs.Superblock, date string, file *os.File) {
 	fmt.Println("======Start CREATE EXT3======")
 	fmt.Println("INODOS:", n)
 
@@ -210,7 +219,7 @@ func create_ext3(n int32, partition Structs.Partition, newSuperblock Structs.Sup
 	fmt.Println("Date:", date)
 
 	// Inicializa el journaling
-	if err := initJournaling(newSuperblock, file); err != nil {
+	if err := initJournaling(file, partitionStart, 0, int64(n)); err != nil {
 		fmt.Println("Error al inicializar el Journaling: ", err)
 		return
 	}
@@ -218,7 +227,7 @@ func create_ext3(n int32, partition Structs.Partition, newSuperblock Structs.Sup
 
 	// Escribe los bitmaps de inodos y bloques en el archivo
 	for i := int32(0); i < n; i++ {
-		if err := Utilities.WriteObject(file, byte(0), int64(newSuperblock.S_bm_inode_start+i)); err != nil {
+		if err := Utilities.WriteObject(file, byte(0), newSuperblock.S_bm_inode_start+int64(i)); err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
@@ -226,7 +235,7 @@ func create_ext3(n int32, partition Structs.Partition, newSuperblock Structs.Sup
 	fmt.Println("Bitmap de inodos escrito correctamente.")
 
 	for i := int32(0); i < 3*n; i++ {
-		if err := Utilities.WriteObject(file, byte(0), int64(newSuperblock.S_bm_block_start+i)); err != nil {
+		if err := Utilities.WriteObject(file, byte(0), newSuperblock.S_bm_block_start+int64(i)); err != nil {
 			fmt.Println("Error: ", err)
 			return
 		}
@@ -248,7 +257,7 @@ func create_ext3(n int32, partition Structs.Partition, newSuperblock Structs.Sup
 	fmt.Println("Carpeta raíz y archivo users.txt creados correctamente.")
 
 	// Escribe el superbloque actualizado al archivo
-	if err := Utilities.WriteObject(file, newSuperblock, int64(partition.Start)); err != nil {
+	if err := Utilities.WriteObject(file, newSuperblock, partitionStart); err != nil {
 		fmt.Println("Error: ", err)
 		return
 	}
@@ -264,7 +273,7 @@ func create_ext3(n int32, partition Structs.Partition, newSuperblock Structs.Sup
 	// Imprimir Fileblocks (similar a EXT2 para verificación)
 	for i := int32(0); i < 1; i++ {
 		var fileblock Structs.Fileblock
-		offset := int64(newSuperblock.S_block_start + int32(binary.Size(Structs.Folderblock{})) + i*int32(binary.Size(Structs.Fileblock{})))
+		offset := newSuperblock.S_block_start + int64(binary.Size(Structs.Folderblock{})) + int64(i)*int64(binary.Size(Structs.Fileblock{}))
 		if err := Utilities.ReadObject(file, &fileblock, offset); err != nil {
 			fmt.Println("Error al leer Fileblock: ", err)
 			return
@@ -276,18 +285,16 @@ func create_ext3(n int32, partition Structs.Partition, newSuperblock Structs.Sup
 	fmt.Println("======End CREATE EXT3======")
 }
 
-// Función para inicializar el journaling
-func initJournaling(newSuperblock Structs.Superblock, file *os.File) error {
+// Función para inicializar las entradas from a to-1 del área del journaling (justo después del Superblock)
+func initJournaling(file *os.File, partitionStart int64, from int64, to int64) error {
 	var journaling Structs.Journaling
 	journaling.Size = 50
 	journaling.Ultimo = 0
 
-	// Calcula la posición de inicio del journaling (después del superbloque y bitmaps)
-	journalingStart := newSuperblock.S_inode_start - int32(binary.Size(Structs.Journaling{}))*journaling.Size
-
-	// Escribir el journaling en el archivo en la ubicación adecuada
-	for i := 0; i < 50; i++ {
-		if err := Utilities.WriteObject(file, journaling, int64(journalingStart+int32(i*binary.Size(journaling)))); err != nil {
+	journalingSize := int64(binary.Size(journaling))
+	journalingStart := partitionStart + int64(binary.Size(Structs.Superblock{}))
+	for i := from; i < to; i++ {
+		if err := Utilities.WriteObject(file, journaling, journalingStart+i*journalingSize); err != nil {
 			return fmt.Errorf("error al inicializar el journaling: %v", err)
 		}
 	}
@@ -296,6 +303,17 @@ func initJournaling(newSuperblock Structs.Superblock, file *os.File) error {
 	return nil
 }
 
+// Función para obtener cuántas entradas tiene el área del journaling de un sistema de archivos: el espacio
+// entre el Superblock y el bitmap de inodos. Es 0 en EXT2 y en los EXT3 creados antes de que el journaling
+// tuviera su propia área, que nunca se reorganizan para agregarla.
+func journalingEntries(superblock Structs.Superblock, partitionStart int64) int64 {
+	if superblock.S_filesystem_type != 3 {
+		return 0
+	}
+	area := superblock.S_bm_inode_start - partitionStart - int64(binary.Size(Structs.Superblock{}))
+	return max(area, 0) / int64(binary.Size(Structs.Journaling{}))
+}
+
 // Función auxiliar para inicializar inodos y bloques
 func initInodesAndBlocks(n int32, newSuperblock Structs.Superblock, file *os.File) error {
 	var newInode Structs.Inode
@@ -304,14 +322,14 @@ func initInodesAndBlocks(n int32, newSuperblock Structs.Superblock, file *os.Fil
 	}
 
 	for i := int32(0); i < n; i++ {
-		if err := Utilities.WriteObject(file, newInode, int64(newSuperblock.S_inode_start+i*int32(binary.Size(Structs.Inode{})))); err != nil {
+		if err := Utilities.WriteObject(file, newInode, newSuperblock.S_inode_start+int64(i)*int64(binary.Size(Structs.Inode{}))); err != nil {
 			return err
 		}
 	}
 
 	var newFileblock Structs.Fileblock
 	for i := int32(0); i < 3*n; i++ {
-		if err := Utilities.WriteObject(file, newFileblock, int64(newSuperblock.S_block_start+i*int32(binary.Size(Structs.Fileblock{})))); err != nil {
+		if err := Utilities.WriteObject(file, newFileblock, newSuperblock.S_block_start+int64(i)*int64(binary.Size(Structs.Fileblock{}))); err != nil {
 			return err
 		}
 	}
@@ -327,6 +345,8 @@ func createRootAndUsersFile(newSuperblock Structs.Superblock, date string, file
 
 	Inode0.I_block[0] = 0
 	Inode1.I_block[0] = 1
+	Inode0.I_type[0] = inodeFolder
+	Inode1.I_type[0] = inodeFile
 
 	// Asignar el tamaño real del contenido
 	data := "1,G,root\n1,U,root,root,123\n"
@@ -359,18 +379,19 @@ func createRootAndUsersFile(newSuperblock Structs.Superblock, date string, file
 	copy(Folderblock0.B_content[1].B_name[:], "..")
 	Folderblock0.B_content[2].B_inodo = 1
 	copy(Folderblock0.B_content[2].B_name[:], "users.txt")
+	Folderblock0.B_content[3].B_inodo = -1
 
 	// Escribir los inodos y bloques en las posiciones correctas
-	if err := Utilities.WriteObject(file, Inode0, int64(newSuperblock.S_inode_start)); err != nil {
+	if err := Utilities.WriteObject(file, Inode0, newSuperblock.S_inode_start); err != nil {
 		return err
 	}
-	if err := Utilities.WriteObject(file, Inode1, int64(newSuperblock.S_inode_start+int32(binary.Size(Structs.Inode{})))); err != nil {
+	if err := Utilities.WriteObject(file, Inode1, newSuperblock.S_inode_start+int64(binary.Size(Structs.Inode{}))); err != nil {
 		return err
 	}
-	if err := Utilities.WriteObject(file, Folderblock0, int64(newSuperblock.S_block_start)); err != nil {
+	if err := Utilities.WriteObject(file, Folderblock0, newSuperblock.S_block_start); err != nil {
 		return err
 	}
-	if err := Utilities.WriteObject(file, Fileblock1, int64(newSuperblock.S_block_start+int32(binary.Size(Structs.Folderblock{})))); err != nil {
+	if err := Utilities.WriteObject(file, Fileblock1, newSuperblock.S_block_start+int64(binary.Size(Structs.Folderblock{}))); err != nil {
 		return err
 	}
 
@@ -394,17 +415,1187 @@ func initInode(inode *Structs.Inode, date string) {
 
 // Función auxiliar para marcar los inodos y bloques usados
 func markUsedInodesAndBlocks(newSuperblock Structs.Superblock, file *os.File) error {
-	if err := Utilities.WriteObject(file, byte(1), int64(newSuperblock.S_bm_inode_start)); err != nil {
+	if err := Utilities.WriteObject(file, byte(1), newSuperblock.S_bm_inode_start); err != nil {
+		return err
+	}
+	if err := Utilities.WriteObject(file, byte(1), newSuperblock.S_bm_inode_start+1); err != nil {
+		return err
+	}
+	if err := Utilities.WriteObject(file, byte(1), newSuperblock.S_bm_block_start); err != nil {
+		return err
+	}
+	if err := Utilities.WriteObject(file, byte(1), newSuperblock.S_bm_block_start+1); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Función para hacer crecer el sistema de archivos de una partición montada hasta ocupar su tamaño actual.
+// Las áreas se mueven de la última a la primera para no sobrescribir datos; los índices de inodos y
+// bloques no cambian, por lo que todos los archivos se conservan.
+func Resizefs(id string) error {
+	fmt.Println("======INICIO RESIZEFS======")
+	fmt.Println("Id:", id)
+
+	mountedPartition, partitionFound := DiskManagement.GetMountedPartition(id)
+	if !partitionFound {
+		fmt.Println("Error: Partición no encontrada")
+		return fmt.Errorf("no se encontró una partición montada con el ID %s", id)
+	}
+
+	file, err := Utilities.OpenFile(mountedPartition.Path)
+	if err != nil {
 		return err
 	}
-	if err := Utilities.WriteObject(file, byte(1), int64(newSuperblock.S_bm_inode_start+1)); err != nil {
+	defer file.Close()
+
+	partition, err := DiskManagement.FindPartitionByID(file, id)
+	if err != nil {
+		fmt.Println("Error: Partición no encontrada en el disco:", err)
 		return err
 	}
-	if err := Utilities.WriteObject(file, byte(1), int64(newSuperblock.S_bm_block_start)); err != nil {
+
+	var oldSuperblock Structs.Superblock
+	if err := Utilities.ReadObject(file, &oldSuperblock, partition.Start); err != nil {
+		fmt.Println("Error al leer el Superblock:", err)
 		return err
 	}
-	if err := Utilities.WriteObject(file, byte(1), int64(newSuperblock.S_bm_block_start+1)); err != nil {
+	if oldSuperblock.S_magic != 0xEF53 {
+		fmt.Println("Error: La partición no tiene un sistema de archivos")
+		return fmt.Errorf("la partición %s no tiene un sistema de archivos", id)
+	}
+
+	// Calcular la nueva distribución con el tamaño actual de la partición, conservando el área
+	// del journaling solo si el sistema de archivos ya la tiene
+	oldJournaling := journalingEntries(oldSuperblock, partition.Start)
+	newSuperblock := oldSuperblock
+	CalculateLayout(&newSuperblock, partition.Start, partition.Size, oldJournaling > 0)
+
+	oldN := oldSuperblock.S_inodes_count
+	newN := newSuperblock.S_inodes_count
+	if newN <= oldN {
+		fmt.Println("El sistema de archivos ya ocupa toda la partición, no hay nada que hacer")
+		fmt.Println("======FIN RESIZEFS======")
+		return nil
+	}
+	fmt.Printf("INODOS: %d -> %d, BLOQUES: %d -> %d\n", oldN, newN, 3*oldN, 3*newN)
+
+	inodeSize := int64(oldSuperblock.S_inode_size)
+	blockSize := int64(oldSuperblock.S_block_size)
+
+	// Mover las áreas existentes a sus nuevas posiciones (de la última a la primera)
+	moves := []struct {
+		src, dst, size int64
+	}{
+		{oldSuperblock.S_block_start, newSuperblock.S_block_start, 3 * int64(oldN) * blockSize},
+		{oldSuperblock.S_inode_start, newSuperblock.S_inode_start, int64(oldN) * inodeSize},
+		{oldSuperblock.S_bm_block_start, newSuperblock.S_bm_block_start, 3 * int64(oldN)},
+		{oldSuperblock.S_bm_inode_start, newSuperblock.S_bm_inode_start, int64(oldN)},
+	}
+	for _, move := range moves {
+		if err := Utilities.MoveBytes(file, move.src, move.dst, move.size); err != nil {
+			fmt.Println("Error al mover el sistema de archivos:", err)
+			return err
+		}
+	}
+
+	// Inicializar el espacio nuevo del journaling, los bitmaps, los inodos y los bloques
+	if oldJournaling > 0 {
+		if err := initJournaling(file, partition.Start, oldJournaling, journalingEntries(newSuperblock, partition.Start)); err != nil {
+			return err
+		}
+	}
+
+	for i := oldN; i < newN; i++ {
+		if err := Utilities.WriteObject(file, byte(0), newSuperblock.S_bm_inode_start+int64(i)); err != nil {
+			return err
+		}
+	}
+
+	for i := 3 * oldN; i < 3*newN; i++ {
+		if err := Utilities.WriteObject(file, byte(0), newSuperblock.S_bm_block_start+int64(i)); err != nil {
+			return err
+		}
+	}
+
+	var newInode Structs.Inode
+	for i := int32(0); i < 15; i++ {
+		newInode.I_block[i] = -1
+	}
+	for i := oldN; i < newN; i++ {
+		if err := Utilities.WriteObject(file, newInode, newSuperblock.S_inode_start+int64(i)*inodeSize); err != nil {
+			return err
+		}
+	}
+
+	var newFileblock Structs.Fileblock
+	for i := 3 * oldN; i < 3*newN; i++ {
+		if err := Utilities.WriteObject(file, newFileblock, newSuperblock.S_block_start+int64(i)*blockSize); err != nil {
+			return err
+		}
+	}
+
+	// Actualizar los contadores y escribir el Superblock
+	newSuperblock.S_free_inodes_count += newN - oldN
+	newSuperblock.S_free_blocks_count += 3 * (newN - oldN)
+	if newSuperblock.S_fist_ino == -1 {
+		newSuperblock.S_fist_ino = oldN
+	}
+	if newSuperblock.S_first_blo == -1 {
+		newSuperblock.S_first_blo = 3 * oldN
+	}
+	if err := Utilities.WriteObject(file, newSuperblock, partition.Start); err != nil {
+		fmt.Println("Error al escribir el Superblock:", err)
 		return err
 	}
+
+	Structs.PrintSuperblock(newSuperblock)
+	fmt.Println("======FIN RESIZEFS======")
 	return nil
 }
+
+// Sistema de archivos de la partición con la sesión iniciada: el disco abierto, el Superblock y el usuario
+type fileSystemSession struct {
+	file       *os.File
+	start      int64
+	superblock Structs.Superblock
+	user       DiskManagement.MountedPartition
+}
+
+// Función para abrir el sistema de archivos de la partición en la que hay una sesión iniciada
+func openSessionFileSystem() (*fileSystemSession, error) {
+	mountedPartition, found := DiskManagement.GetLoggedInPartition()
+	if !found {
+		return nil, fmt.Errorf("no hay ninguna sesión iniciada, use login primero")
+	}
+
+	file, err := Utilities.OpenFile(mountedPartition.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	partition, err := DiskManagement.FindPartitionByID(file, mountedPartition.ID)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	var superblock Structs.Superblock
+	if err := Utilities.ReadObject(file, &superblock, partition.Start); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("no se pudo leer el Superblock: %v", err)
+	}
+	if superblock.S_magic != 0xEF53 {
+		file.Close()
+		return nil, fmt.Errorf("la partición %s no tiene un sistema de archivos", mountedPartition.ID)
+	}
+
+	return &fileSystemSession{file: file, start: partition.Start, superblock: superblock, user: mountedPartition}, nil
+}
+
+func (s *fileSystemSession) Close() {
+	s.file.Close()
+}
+
+func (s *fileSystemSession) readInode(index int32) (Structs.Inode, error) {
+	var inode Structs.Inode
+	err := Utilities.ReadObject(s.file, &inode, s.superblock.S_inode_start+int64(index)*int64(s.superblock.S_inode_size))
+	return inode, err
+}
+
+func (s *fileSystemSession) writeInode(index int32, inode Structs.Inode) error {
+	return Utilities.WriteObject(s.file, inode, s.superblock.S_inode_start+int64(index)*int64(s.superblock.S_inode_size))
+}
+
+// Función para leer un bloque (Folderblock, Fileblock o Pointerblock) por su índice
+func (s *fileSystemSession) readBlock(index int32, block interface{}) error {
+	if index < 0 || index >= s.superblock.S_blocks_count {
+		return fmt.Errorf("el apuntador a bloque %d está fuera del sistema de archivos", index)
+	}
+	return Utilities.ReadObject(s.file, block, s.superblock.S_block_start+int64(index)*int64(s.superblock.S_block_size))
+}
+
+func (s *fileSystemSession) writeBlock(index int32, block interface{}) error {
+	return Utilities.WriteObject(s.file, block, s.superblock.S_block_start+int64(index)*int64(s.superblock.S_block_size))
+}
+
+func (s *fileSystemSession) saveSuperblock() error {
+	return Utilities.WriteObject(s.file, s.superblock, s.start)
+}
+
+// Función para buscar el primer elemento libre de un bitmap desde una posición, dando la vuelta al inicio.
+// Retorna -1 si el bitmap está lleno.
+func (s *fileSystemSession) findFreeInBitmap(bitmapStart int64, count int32, from int32) (int32, error) {
+	if from < 0 || from >= count {
+		from = 0
+	}
+
+	buffer := make([]byte, 64*1024)
+	for _, area := range [][2]int32{{from, count}, {0, from}} {
+		for position := area[0]; position < area[1]; {
+			length := int32(len(buffer))
+			if area[1]-position < length {
+				length = area[1] - position
+			}
+			if _, err := s.file.ReadAt(buffer[:length], bitmapStart+int64(position)); err != nil {
+				return -1, err
+			}
+			if i := bytes.IndexByte(buffer[:length], 0); i >= 0 {
+				return position + int32(i), nil
+			}
+			position += length
+		}
+	}
+	return -1, nil
+}
+
+// Función para reservar un elemento de un bitmap, actualizando el contador de libres y el primer libre del Superblock
+func (s *fileSystemSession) allocate(bitmapStart int64, count int32, first *int32, free *int32, kind string) (int32, error) {
+	index, err := s.findFreeInBitmap(bitmapStart, count, *first)
+	if err != nil {
+		return -1, err
+	}
+	if index == -1 {
+		return -1, fmt.Errorf("no quedan %s libres en la partición", kind)
+	}
+
+	if err := Utilities.WriteObject(s.file, byte(1), bitmapStart+int64(index)); err != nil {
+		return -1, err
+	}
+
+	next, err := s.findFreeInBitmap(bitmapStart, count, index+1)
+	if err != nil {
+		return -1, err
+	}
+	*first = next
+	*free--
+
+	return index, s.saveSuperblock()
+}
+
+// Función para liberar un elemento de un bitmap, actualizando el contador de libres y el primer libre del Superblock
+func (s *fileSystemSession) release(bitmapStart int64, index int32, first *int32, free *int32) error {
+	if err := Utilities.WriteObject(s.file, byte(0), bitmapStart+int64(index)); err != nil {
+		return err
+	}
+	if *first == -1 || index < *first {
+		*first = index
+	}
+	*free++
+	return s.saveSuperblock()
+}
+
+func (s *fileSystemSession) freeInode(index int32) error {
+	sb := &s.superblock
+	return s.release(sb.S_bm_inode_start, index, &sb.S_fist_ino, &sb.S_free_inodes_count)
+}
+
+func (s *fileSystemSession) freeBlock(index int32) error {
+	sb := &s.superblock
+	return s.release(sb.S_bm_block_start, index, &sb.S_first_blo, &sb.S_free_blocks_count)
+}
+
+func (s *fileSystemSession) allocateInode() (int32, error) {
+	sb := &s.superblock
+	return s.allocate(sb.S_bm_inode_start, sb.S_inodes_count, &sb.S_fist_ino, &sb.S_free_inodes_count, "inodos")
+}
+
+func (s *fileSystemSession) allocateBlock() (int32, error) {
+	sb := &s.superblock
+	return s.allocate(sb.S_bm_block_start, sb.S_blocks_count, &sb.S_first_blo, &sb.S_free_blocks_count, "bloques")
+}
+
+// Función para crear un inodo nuevo con el usuario de la sesión como propietario
+func (s *fileSystemSession) newInode(type_ byte) Structs.Inode {
+	var inode Structs.Inode
+	initInode(&inode, currentDate())
+	inode.I_uid = s.user.UID
+	inode.I_gid = s.user.GID
+	inode.I_type[0] = type_
+	return inode
+}
+
+// Función para obtener la fecha actual en el formato de los inodos
+func currentDate() string {
+	return time.Now().Format("02/01/2006 15:04")
+}
+
+// Función para saber si un inodo es una carpeta (la raíz de discos formateados antes de guardar I_type también lo es)
+func isFolder(index int32, inode Structs.Inode) bool {
+	return inode.I_type[0] == inodeFolder || index == 0
+}
+
+// Función para obtener el nombre de una entrada de un Folderblock
+func contentName(content Structs.Content) string {
+	return strings.TrimRight(string(content.B_name[:]), "\x00")
+}
+
+// Función para separar una ruta absoluta en los nombres de cada carpeta o archivo
+func splitPath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("la ruta %s debe ser absoluta", path)
+	}
+
+	var names []string
+	for _, name := range strings.Split(path, "/") {
+		if name == "" {
+			continue
+		}
+		if name == "." || name == ".." {
+			return nil, fmt.Errorf("la ruta %s no puede contener . ni ..", path)
+		}
+		if len(name) > maxNameLength {
+			return nil, fmt.Errorf("el nombre %s excede los %d caracteres", name, maxNameLength)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// Función para buscar una entrada por nombre dentro de una carpeta. Retorna -1 si no existe.
+func (s *fileSystemSession) lookup(folder Structs.Inode, name string) (int32, error) {
+	blocks, err := s.inodeBlocks(folder)
+	if err != nil {
+		return -1, err
+	}
+	for _, blockIndex := range blocks {
+		var block Structs.Folderblock
+		if err := s.readBlock(blockIndex, &block); err != nil {
+			return -1, err
+		}
+		for _, content := range block.B_content {
+			if !isFreeContent(content) && contentName(content) == name {
+				return content.B_inodo, nil
+			}
+		}
+	}
+	return -1, nil
+}
+
+// Función para saber si una entrada de un Folderblock está libre: nunca se usó (nombre vacío) o se liberó al borrar (B_inodo -1)
+func isFreeContent(content Structs.Content) bool {
+	return content.B_name[0] == 0 || content.B_inodo == -1
+}
+
+// Función para buscar una entrada libre en cualquiera de los bloques de una carpeta, reutilizando las que se liberaron.
+// Solo busca, no modifica la carpeta; retorna el bloque -1 si todos sus bloques están llenos.
+func (s *fileSystemSession) findFolderSlot(folder Structs.Inode) (int32, int, error) {
+	blocks, err := s.inodeBlocks(folder)
+	if err != nil {
+		return -1, -1, err
+	}
+	for _, blockIndex := range blocks {
+		var block Structs.Folderblock
+		if err := s.readBlock(blockIndex, &block); err != nil {
+			return -1, -1, err
+		}
+		for slot, content := range block.B_content {
+			if isFreeContent(content) {
+				return blockIndex, slot, nil
+			}
+		}
+	}
+	return -1, -1, nil
+}
+
+// Función para calcular cómo crecería una carpeta llena: la posición lógica del Folderblock nuevo
+// (la primera sin bloque) y cuántos bloques hay que reservar para él, contando los Pointerblocks nuevos
+func (s *fileSystemSession) folderGrowth(folder Structs.Inode) (int32, int32, error) {
+	logical := int32(0)
+	for ; logical < maxInodeBlocks; logical++ {
+		index, err := s.inodeBlock(&folder, logical, false)
+		if err != nil {
+			return -1, 0, err
+		}
+		if index == -1 {
+			break
+		}
+	}
+	if logical == maxInodeBlocks {
+		return -1, 0, fmt.Errorf("la carpeta ya no tiene espacio para más entradas")
+	}
+	return logical, 1 + pointerBlocksFor(logical+1) - pointerBlocksFor(logical), nil
+}
+
+// Función para agregar un Folderblock vacío a una carpeta en la posición lógica indicada por folderGrowth.
+// El llamador debe verificar antes que alcancen los bloques.
+func (s *fileSystemSession) growFolder(folderIndex int32, logical int32) (int32, error) {
+	folder, err := s.readInode(folderIndex)
+	if err != nil {
+		return -1, err
+	}
+	blockIndex, err := s.inodeBlock(&folder, logical, true)
+	if err != nil {
+		return -1, err
+	}
+	var block Structs.Folderblock
+	for i := range block.B_content {
+		block.B_content[i].B_inodo = -1
+	}
+	if err := s.writeBlock(blockIndex, block); err != nil {
+		return -1, err
+	}
+	if err := s.writeInode(folderIndex, folder); err != nil {
+		return -1, err
+	}
+	return blockIndex, nil
+}
+
+// Función para reservar la entrada de un elemento nuevo en una carpeta. Antes de modificar nada se verifica
+// que alcancen un inodo y los bloques del elemento (blocks) más los que necesite la carpeta si está llena;
+// así un error por falta de espacio no deja bloques reservados sin usar.
+func (s *fileSystemSession) reserveFolderSlot(folderIndex int32, blocks int32) (int32, int, error) {
+	folder, err := s.readInode(folderIndex)
+	if err != nil {
+		return -1, -1, err
+	}
+	blockIndex, slot, err := s.findFolderSlot(folder)
+	if err != nil {
+		return -1, -1, err
+	}
+
+	logical, needed := int32(-1), blocks
+	if blockIndex == -1 {
+		var growth int32
+		if logical, growth, err = s.folderGrowth(folder); err != nil {
+			return -1, -1, err
+		}
+		needed += growth
+	}
+	if s.superblock.S_free_inodes_count < 1 {
+		return -1, -1, fmt.Errorf("no quedan inodos libres en la partición")
+	}
+	if needed > s.superblock.S_free_blocks_count {
+		return -1, -1, fmt.Errorf("no hay espacio suficiente: se necesitan %d bloques y quedan %d", needed, s.superblock.S_free_blocks_count)
+	}
+
+	if blockIndex == -1 {
+		if blockIndex, err = s.growFolder(folderIndex, logical); err != nil {
+			return -1, -1, err
+		}
+		slot = 0
+	}
+	return blockIndex, slot, nil
+}
+
+// Función para agregar una entrada a una carpeta
+func (s *fileSystemSession) addEntry(folderIndex int32, blockIndex int32, slot int, name string, inodeIndex int32) error {
+	var block Structs.Folderblock
+	if err := s.readBlock(blockIndex, &block); err != nil {
+		return err
+	}
+	block.B_content[slot] = Structs.Content{B_inodo: inodeIndex}
+	copy(block.B_content[slot].B_name[:], name)
+	if err := s.writeBlock(blockIndex, block); err != nil {
+		return err
+	}
+
+	// Actualizar la fecha de modificación de la carpeta
+	folder, err := s.readInode(folderIndex)
+	if err != nil {
+		return err
+	}
+	copy(folder.I_mtime[:], currentDate())
+	return s.writeInode(folderIndex, folder)
+}
+
+// Función para crear una carpeta vacía (con sus entradas . y ..) dentro de otra
+func (s *fileSystemSession) createFolder(parentIndex int32, name string) (int32, error) {
+	// Reservar primero la entrada en la carpeta padre (la carpeta nueva usa un Folderblock)
+	parentBlock, slot, err := s.reserveFolderSlot(parentIndex, 1)
+	if err != nil {
+		return -1, err
+	}
+
+	inodeIndex, err := s.allocateInode()
+	if err != nil {
+		return -1, err
+	}
+	blockIndex, err := s.allocateBlock()
+	if err != nil {
+		return -1, err
+	}
+
+	var block Structs.Folderblock
+	for i := range block.B_content {
+		block.B_content[i].B_inodo = -1
+	}
+	block.B_content[0].B_inodo = inodeIndex
+	copy(block.B_content[0].B_name[:], ".")
+	block.B_content[1].B_inodo = parentIndex
+	copy(block.B_content[1].B_name[:], "..")
+	if err := s.writeBlock(blockIndex, block); err != nil {
+		return -1, err
+	}
+
+	inode := s.newInode(inodeFolder)
+	inode.I_block[0] = blockIndex
+	if err := s.writeInode(inodeIndex, inode); err != nil {
+		return -1, err
+	}
+
+	if err := s.addEntry(parentIndex, parentBlock, slot, name, inodeIndex); err != nil {
+		return -1, err
+	}
+	return inodeIndex, nil
+}
+
+// Función para crear una carpeta en la partición con la sesión iniciada.
+// Con -p se crean también las carpetas padre que no existan.
+func Mkdir(path string, p bool) error {
+	fmt.Println("======INICIO MKDIR======")
+	fmt.Println("Path:", path)
+	fmt.Println("P:", p)
+
+	if err := mkdir(path, p); err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+
+	fmt.Println("======FIN MKDIR======")
+	return nil
+}
+
+func mkdir(path string, p bool) error {
+	names, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("la carpeta raíz ya existe")
+	}
+
+	s, err := openSessionFileSystem()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	parentIndex, err := s.walkFolders(names[:len(names)-1], p)
+	if err != nil {
+		return err
+	}
+
+	name := names[len(names)-1]
+	parent, err := s.readInode(parentIndex)
+	if err != nil {
+		return err
+	}
+	child, err := s.lookup(parent, name)
+	if err != nil {
+		return err
+	}
+	if child != -1 {
+		childInode, err := s.readInode(child)
+		if err != nil {
+			return err
+		}
+		if !isFolder(child, childInode) {
+			return fmt.Errorf("%s ya existe y no es una carpeta", path)
+		}
+		if !p {
+			return fmt.Errorf("la carpeta %s ya existe", path)
+		}
+		return nil
+	}
+
+	if !s.hasPermission(parent, permissionWrite) {
+		return fmt.Errorf("el usuario %s no tiene permiso de escritura en la carpeta padre de %s", s.user.User, path)
+	}
+	child, err = s.createFolder(parentIndex, name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Carpeta creada: %s (inodo %d)\n", path, child)
+	return nil
+}
+
+// Función para recorrer las carpetas de una ruta desde la raíz y retornar el inodo de la última.
+// Con create se crean las carpetas que no existan.
+func (s *fileSystemSession) walkFolders(names []string, create bool) (int32, error) {
+	current := int32(0)
+	for i, name := range names {
+		folderPath := "/" + strings.Join(names[:i+1], "/")
+
+		folder, err := s.readInode(current)
+		if err != nil {
+			return -1, err
+		}
+		child, err := s.lookup(folder, name)
+		if err != nil {
+			return -1, err
+		}
+
+		if child != -1 {
+			childInode, err := s.readInode(child)
+			if err != nil {
+				return -1, err
+			}
+			if !isFolder(child, childInode) {
+				return -1, fmt.Errorf("%s no es una carpeta", folderPath)
+			}
+			current = child
+			continue
+		}
+
+		if !create {
+			return -1, fmt.Errorf("la carpeta %s no existe", folderPath)
+		}
+		if !s.hasPermission(folder, permissionWrite) {
+			return -1, fmt.Errorf("el usuario %s no tiene permiso de escritura para crear %s", s.user.User, folderPath)
+		}
+		child, err = s.createFolder(current, name)
+		if err != nil {
+			return -1, err
+		}
+		fmt.Printf("Carpeta creada: %s (inodo %d)\n", folderPath, child)
+		current = child
+	}
+	return current, nil
+}
+
+// Función para verificar si el usuario de la sesión tiene un permiso sobre un inodo.
+// I_perm guarda un dígito para el propietario, otro para el grupo y otro para los demás; root tiene todos los permisos.
+func (s *fileSystemSession) hasPermission(inode Structs.Inode, permission byte) bool {
+	if s.user.User == "root" {
+		return true
+	}
+
+	digit := inode.I_perm[2]
+	if inode.I_uid == s.user.UID {
+		digit = inode.I_perm[0]
+	} else if inode.I_gid == s.user.GID {
+		digit = inode.I_perm[1]
+	}
+	if digit < '0' || digit > '7' {
+		return false
+	}
+	return (digit-'0')&permission != 0
+}
+
+// Función para obtener el bloque físico donde está el bloque lógico número logical de un inodo.
+// Los primeros 12 son directos y luego siguen el apuntador indirecto simple, el doble y el triple.
+// Con allocate se reservan los bloques (de datos y de apuntadores) que falten; el llamador debe guardar el inodo.
+func (s *fileSystemSession) inodeBlock(inode *Structs.Inode, logical int32, allocate bool) (int32, error) {
+	if logical < directBlocks {
+		if inode.I_block[logical] == -1 && allocate {
+			index, err := s.allocateBlock()
+			if err != nil {
+				return -1, err
+			}
+			inode.I_block[logical] = index
+		}
+		return inode.I_block[logical], nil
+	}
+
+	logical -= directBlocks
+	span := int32(1)
+	for level := int32(1); level <= 3; level++ {
+		span *= pointersPerBlock
+		if logical < span {
+			return s.indirectBlock(&inode.I_block[directBlocks+level-1], level, logical, allocate)
+		}
+		logical -= span
+	}
+	return -1, fmt.Errorf("se excede el máximo de bloques de un inodo")
+}
+
+// Función para recorrer un Pointerblock del nivel indicado (1 simple, 2 doble, 3 triple) hasta el bloque de datos
+func (s *fileSystemSession) indirectBlock(pointer *int32, level int32, logical int32, allocate bool) (int32, error) {
+	if *pointer == -1 {
+		if !allocate {
+			return -1, nil
+		}
+		index, err := s.allocateBlock()
+		if err != nil {
+			return -1, err
+		}
+		var empty Structs.Pointerblock
+		for i := range empty.B_pointers {
+			empty.B_pointers[i] = -1
+		}
+		if err := s.writeBlock(index, empty); err != nil {
+			return -1, err
+		}
+		*pointer = index
+	}
+
+	var block Structs.Pointerblock
+	if err := s.readBlock(*pointer, &block); err != nil {
+		return -1, err
+	}
+
+	// Cantidad de bloques de datos que cubre cada apuntador de este nivel
+	span := int32(1)
+	for i := int32(1); i < level; i++ {
+		span *= pointersPerBlock
+	}
+	slot := logical / span
+	next := block.B_pointers[slot]
+
+	var result int32
+	if level == 1 {
+		if next == -1 && allocate {
+			index, err := s.allocateBlock()
+			if err != nil {
+				return -1, err
+			}
+			next = index
+		}
+		result = next
+	} else {
+		var err error
+		if result, err = s.indirectBlock(&next, level-1, logical%span, allocate); err != nil {
+			return -1, err
+		}
+	}
+
+	if next != block.B_pointers[slot] {
+		block.B_pointers[slot] = next
+		if err := s.writeBlock(*pointer, block); err != nil {
+			return -1, err
+		}
+	}
+	return result, nil
+}
+
+// Función para obtener en orden los bloques de datos de un inodo, recorriendo los apuntadores directos e indirectos
+func (s *fileSystemSession) inodeBlocks(inode Structs.Inode) ([]int32, error) {
+	var blocks []int32
+	for _, index := range inode.I_block[:directBlocks] {
+		if index != -1 {
+			blocks = append(blocks, index)
+		}
+	}
+	for level := int32(1); level <= 3; level++ {
+		if err := s.collectIndirect(inode.I_block[directBlocks+level-1], level, &blocks); err != nil {
+			return nil, err
+		}
+	}
+	return blocks, nil
+}
+
+func (s *fileSystemSession) collectIndirect(pointer int32, level int32, blocks *[]int32) error {
+	if pointer == -1 {
+		return nil
+	}
+	var block Structs.Pointerblock
+	if err := s.readBlock(pointer, &block); err != nil {
+		return err
+	}
+	for _, next := range block.B_pointers {
+		if next == -1 {
+			continue
+		}
+		if level == 1 {
+			*blocks = append(*blocks, next)
+		} else if err := s.collectIndirect(next, level-1, blocks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Función para liberar los bloques de un inodo desde el bloque lógico keep en adelante,
+// junto con los Pointerblocks que queden vacíos. El llamador debe guardar el inodo.
+func (s *fileSystemSession) releaseBlocks(inode *Structs.Inode, keep int32) error {
+	for i := keep; i < directBlocks; i++ {
+		if inode.I_block[i] == -1 {
+			continue
+		}
+		if err := s.freeBlock(inode.I_block[i]); err != nil {
+			return err
+		}
+		inode.I_block[i] = -1
+	}
+
+	first := int32(directBlocks)
+	span := int32(1)
+	for level := int32(1); level <= 3; level++ {
+		span *= pointersPerBlock
+		from := keep - first
+		if from < 0 {
+			from = 0
+		}
+		if from < span {
+			if err := s.releaseIndirect(&inode.I_block[directBlocks+level-1], level, from); err != nil {
+				return err
+			}
+		}
+		first += span
+	}
+	return nil
+}
+
+// Función para liberar los bloques de un Pointerblock desde el bloque lógico from (relativo a él).
+// Si no conserva ninguno, también se libera el Pointerblock.
+func (s *fileSystemSession) releaseIndirect(pointer *int32, level int32, from int32) error {
+	if *pointer == -1 {
+		return nil
+	}
+
+	var block Structs.Pointerblock
+	if err := s.readBlock(*pointer, &block); err != nil {
+		return err
+	}
+
+	span := int32(1)
+	for i := int32(1); i < level; i++ {
+		span *= pointersPerBlock
+	}
+
+	changed := false
+	for slot := int32(0); slot < pointersPerBlock; slot++ {
+		start := slot * span
+		if block.B_pointers[slot] == -1 || start+span <= from {
+			continue
+		}
+		old := block.B_pointers[slot]
+		if level == 1 {
+			if err := s.freeBlock(old); err != nil {
+				return err
+			}
+			block.B_pointers[slot] = -1
+		} else {
+			sub := from - start
+			if sub < 0 {
+				sub = 0
+			}
+			if err := s.releaseIndirect(&block.B_pointers[slot], level-1, sub); err != nil {
+				return err
+			}
+		}
+		changed = changed || block.B_pointers[slot] != old
+	}
+
+	if from == 0 {
+		if err := s.freeBlock(*pointer); err != nil {
+			return err
+		}
+		*pointer = -1
+		return nil
+	}
+	if changed {
+		return s.writeBlock(*pointer, block)
+	}
+	return nil
+}
+
+// Función para calcular cuántos bloques de apuntadores se necesitan para un archivo de count bloques de datos
+func pointerBlocksFor(count int32) int32 {
+	count -= directBlocks
+	total := int32(0)
+	span := int32(1)
+	for level := int32(1); level <= 3 && count > 0; level++ {
+		span *= pointersPerBlock
+		used := count
+		if used > span {
+			used = span
+		}
+		// En cada nivel hace falta un Pointerblock por cada grupo de 16, 256 o 4096 bloques de datos
+		for group := int32(pointersPerBlock); group <= span; group *= pointersPerBlock {
+			total += (used + group - 1) / group
+		}
+		count -= used
+	}
+	return total
+}
+
+// Función para escribir el contenido de un archivo en sus Fileblocks, usando los apuntadores indirectos si hace falta.
+// Reemplaza el contenido anterior del inodo.
+func (s *fileSystemSession) writeFileData(inode *Structs.Inode, data []byte) error {
+	blockSize := int(s.superblock.S_block_size)
+	for logical := 0; logical*blockSize < len(data); logical++ {
+		index, err := s.inodeBlock(inode, int32(logical), true)
+		if err != nil {
+			return err
+		}
+
+		var block Structs.Fileblock
+		copy(block.B_content[:], data[logical*blockSize:])
+		if err := s.writeBlock(index, block); err != nil {
+			return err
+		}
+	}
+
+	// Si el contenido anterior era más grande se liberan los bloques que sobran
+	used := int32((len(data) + blockSize - 1) / blockSize)
+	if err := s.releaseBlocks(inode, used); err != nil {
+		return err
+	}
+	inode.I_size = int32(len(data))
+	copy(inode.I_mtime[:], currentDate())
+	return nil
+}
+
+// Función para crear un archivo con su contenido dentro de una carpeta
+func (s *fileSystemSession) createFile(parentIndex int32, name string, data []byte) (int32, error) {
+	blockSize := int64(s.superblock.S_block_size)
+	dataBlocks := int32((int64(len(data)) + blockSize - 1) / blockSize)
+	if int64(dataBlocks) > maxInodeBlocks {
+		return -1, fmt.Errorf("el archivo de %d bytes excede el máximo de %d bytes", len(data), maxInodeBlocks*blockSize)
+	}
+
+	// Reservar primero la entrada en la carpeta padre, verificando que alcancen los bloques del archivo
+	parentBlock, slot, err := s.reserveFolderSlot(parentIndex, dataBlocks+pointerBlocksFor(dataBlocks))
+	if err != nil {
+		return -1, err
+	}
+
+	inodeIndex, err := s.allocateInode()
+	if err != nil {
+		return -1, err
+	}
+	inode := s.newInode(inodeFile)
+	if err := s.writeFileData(&inode, data); err != nil {
+		return -1, err
+	}
+	if err := s.writeInode(inodeIndex, inode); err != nil {
+		return -1, err
+	}
+
+	if err := s.addEntry(parentIndex, parentBlock, slot, name, inodeIndex); err != nil {
+		return -1, err
+	}
+	return inodeIndex, nil
+}
+
+// Función para crear un archivo en la partición con la sesión iniciada. El contenido se toma del archivo
+// cont de la computadora o, si no se indica, se generan size bytes con los dígitos 0123456789.
+// Con -r se crean también las carpetas padre que no existan.
+func Mkfile(path string, size int, r bool, cont string) error {
+	fmt.Println("======INICIO MKFILE======")
+	fmt.Println("Path:", path)
+	fmt.Println("Size:", size)
+	fmt.Println("R:", r)
+	fmt.Println("Cont:", cont)
+
+	if err := mkfile(path, size, r, cont); err != nil {
+		fmt.Println("Error:", err)
+		return err
+	}
+
+	fmt.Println("======FIN MKFILE======")
+	return nil
+}
+
+func mkfile(path string, size int, r bool, cont string) error {
+	if size < 0 {
+		return fmt.Errorf("el tamaño no puede ser negativo")
+	}
+
+	names, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("la ruta %s no es un archivo", path)
+	}
+
+	// El contenido de cont tiene prioridad sobre size
+	var data []byte
+	if cont != "" {
+		if data, err = os.ReadFile(cont); err != nil {
+			return fmt.Errorf("no se pudo leer el archivo %s: %v", cont, err)
+		}
+	} else {
+		data = make([]byte, size)
+		for i := range data {
+			data[i] = byte('0' + i%10)
+		}
+	}
+
+	s, err := openSessionFileSystem()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	parentIndex, err := s.walkFolders(names[:len(names)-1], r)
+	if err != nil {
+		return err
+	}
+
+	name := names[len(names)-1]
+	parent, err := s.readInode(parentIndex)
+	if err != nil {
+		return err
+	}
+	child, err := s.lookup(parent, name)
+	if err != nil {
+		return err
+	}
+	if child != -1 {
+		return fmt.Errorf("%s ya existe", path)
+	}
+
+	if !s.hasPermission(parent, permissionWrite) {
+		return fmt.Errorf("el usuario %s no tiene permiso de escritura en la carpeta padre de %s", s.user.User, path)
+	}
+	child, err = s.createFile(parentIndex, name, data)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Archivo creado: %s (inodo %d, %d bytes)\n", path, child, len(data))
+	return nil
+}
+
+// Función para buscar el inodo de una ruta absoluta
+func (s *fileSystemSession) resolvePath(path string) (int32, error) {
+	names, err := splitPath(path)
+	if err != nil {
+		return -1, err
+	}
+	if len(names) == 0 {
+		return 0, nil
+	}
+
+	parentIndex, err := s.walkFolders(names[:len(names)-1], false)
+	if err != nil {
+		return -1, err
+	}
+	parent, err := s.readInode(parentIndex)
+	if err != nil {
+		return -1, err
+	}
+	index, err := s.lookup(parent, names[len(names)-1])
+	if err != nil {
+		return -1, err
+	}
+	if index == -1 {
+		return -1, fmt.Errorf("%s no existe", path)
+	}
+	return index, nil
+}
+
+// Función para leer exactamente los I_size bytes de un archivo, siguiendo los apuntadores directos e indirectos
+func (s *fileSystemSession) readFileData(inode Structs.Inode) ([]byte, error) {
+	blockSize := int(s.superblock.S_block_size)
+	data := make([]byte, 0, inode.I_size)
+	for logical := 0; len(data) < int(inode.I_size); logical++ {
+		index, err := s.inodeBlock(&inode, int32(logical), false)
+		if err != nil {
+			return nil, err
+		}
+
+		// Un bloque sin asignar se lee como ceros
+		var block Structs.Fileblock
+		if index != -1 {
+			if err := s.readBlock(index, &block); err != nil {
+				return nil, err
+			}
+		}
+
+		length := int(inode.I_size) - len(data)
+		if length > blockSize {
+			length = blockSize
+		}
+		data = append(data, block.B_content[:length]...)
+	}
+	return data, nil
+}
+
+// Función para mostrar el contenido de uno o varios archivos de la partición con la sesión iniciada,
+// concatenados en el orden recibido
+func Cat(files []string) (string, error) {
+	fmt.Println("======INICIO CAT======")
+	fmt.Println("Files:", files)
+
+	content, err := cat(files)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return "", err
+	}
+
+	fmt.Print(content)
+	if content != "" && content[len(content)-1] != '\n' {
+		fmt.Println()
+	}
+	fmt.Println("======FIN CAT======")
+	return content, nil
+}
+
+func cat(files []string) (string, error) {
+	if len(files) == 0 {
+		return "", fmt.Errorf("se necesita al menos un archivo")
+	}
+
+	s, err := openSessionFileSystem()
+	if err != nil {
+		return "", err
+	}
+	defer s.Close()
+
+	var content strings.Builder
+	for _, path := range files {
+		index, err := s.resolvePath(path)
+		if err != nil {
+			return "", err
+		}
+		inode, err := s.readInode(index)
+		if err != nil {
+			return "", err
+		}
+		if isFolder(index, inode) {
+			return "", fmt.Errorf("%s es una carpeta", path)
+		}
+		if !s.hasPermission(inode, permissionRead) {
+			return "", fmt.Errorf("el usuario %s no tiene permiso de lectura sobre %s", s.user.User, path)
+		}
+
+		data, err := s.readFileData(inode)
+		if err != nil {
+			return "", err
+		}
+		content.Write(data)
+	}
+	return content.String(), nil
+}
+
+// Función para leer el contenido completo (I_size bytes) de un inodo de archivo
+func ReadInodeData(file *os.File, superblock Structs.Superblock, inode Structs.Inode) ([]byte, error) {
+	s := &fileSystemSession{file: file, superblock: superblock}
+	return s.readFileData(inode)
+}
+
+// Función para obtener en orden los bloques de datos de un inodo (directos e indirectos)
+func InodeBlocks(file *os.File, superblock Structs.Superblock, inode Structs.Inode) ([]int32, error) {
+	s := &fileSystemSession{file: file, superblock: superblock}
+	return s.inodeBlocks(inode)
+}
+
+// Función para reemplazar el contenido de un archivo de la partición que inicia en partitionStart,
+// reservando o liberando los bloques que hagan falta
+func WriteInodeData(file *os.File, partitionStart int64, inodeIndex int32, data []byte) error {
+	s := &fileSystemSession{file: file, start: partitionStart}
+	if err := Utilities.ReadObject(file, &s.superblock, partitionStart); err != nil {
+		return err
+	}
+
+	inode, err := s.readInode(inodeIndex)
+	if err != nil {
+		return err
+	}
+
+	blockSize := int64(s.superblock.S_block_size)
+	dataBlocks := int32((int64(len(data)) + blockSize - 1) / blockSize)
+	if int64(dataBlocks) > maxInodeBlocks {
+		return fmt.Errorf("el contenido de %d bytes excede el máximo de %d bytes", len(data), maxInodeBlocks*blockSize)
+	}
+
+	// Verificar que alcancen los bloques que faltan antes de reservar cualquiera
+	current, err := s.inodeBlocks(inode)
+	if err != nil {
+		return err
+	}
+	currentBlocks := int32(len(current))
+	needed := dataBlocks + pointerBlocksFor(dataBlocks) - currentBlocks - pointerBlocksFor(currentBlocks)
+	if needed > s.superblock.S_free_blocks_count {
+		return fmt.Errorf("no hay espacio suficiente: se necesitan %d bloques y quedan %d", needed, s.superblock.S_free_blocks_count)
+	}
+
+	if err := s.writeFileData(&inode, data); err != nil {
+		return err
+	}
+	return s.writeInode(inodeIndex, inode)
+}
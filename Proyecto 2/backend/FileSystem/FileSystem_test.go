@@ -0,0 +1,474 @@
+package FileSystem
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"proyecto1/DiskManagement"
+	"proyecto1/Structs"
+	"proyecto1/Utilities"
+)
+
+func TestMain(m *testing.M) {
+	// El registro de montajes se guarda en un directorio temporal y no en el directorio del paquete
+	dir, err := os.MkdirTemp("", "filesystem")
+	if err != nil {
+		panic(err)
+	}
+	DiskManagement.SetMountRegistryPath(filepath.Join(dir, "mount_registry.json"))
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// Partición formateada y con sesión de root para las pruebas
+type testPartition struct {
+	path string
+	name string
+	id   string
+}
+
+// Función para crear un disco con una partición primaria formateada con fs (2fs o 3fs) e iniciar sesión como root
+// El disco deja libre después de la partición otro tanto de su tamaño para poder agrandarla
+func newTestPartition(t *testing.T, sizeKB int, fs string) testPartition {
+	t.Helper()
+	DiskManagement.CleanMountedPartitions()
+
+	partition := testPartition{path: filepath.Join(t.TempDir(), "disk.mia"), name: "p1"}
+	DiskManagement.Mkdisk(2*sizeKB+64, "ff", "k", partition.path, "mbr", "sparse")
+	if err := DiskManagement.Fdisk(sizeKB, partition.path, partition.name, "k", "p", ""); err != nil {
+		t.Fatalf("fdisk: %v", err)
+	}
+	DiskManagement.Mount(partition.path, partition.name)
+	for _, mounted := range DiskManagement.GetMountedPartitions() {
+		for _, candidate := range mounted {
+			if candidate.Path == partition.path && candidate.Name == partition.name {
+				partition.id = candidate.ID
+			}
+		}
+	}
+	if partition.id == "" {
+		t.Fatal("no se pudo montar la partición")
+	}
+
+	Mkfs(partition.id, "full", fs)
+	DiskManagement.MarkPartitionAsLoggedIn(partition.id, "root", 1, 1)
+	return partition
+}
+
+// Función para leer el Superblock y el inicio de la partición de prueba
+func (p testPartition) superblock(t *testing.T) (Structs.Superblock, int64) {
+	t.Helper()
+	file, err := Utilities.OpenFile(p.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	location, err := DiskManagement.FindPartitionByID(file, p.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var superblock Structs.Superblock
+	if err := Utilities.ReadObject(file, &superblock, location.Start); err != nil || superblock.S_magic != 0xEF53 {
+		t.Fatalf("la partición no tiene un Superblock válido")
+	}
+	return superblock, location.Start
+}
+
+// Función para leer la entrada index del área del journaling
+func (p testPartition) journaling(t *testing.T, start int64, index int64) Structs.Journaling {
+	t.Helper()
+	file, err := Utilities.OpenFile(p.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var journaling Structs.Journaling
+	position := start + int64(binary.Size(Structs.Superblock{})) + index*int64(binary.Size(journaling))
+	if err := Utilities.ReadObject(file, &journaling, position); err != nil {
+		t.Fatal(err)
+	}
+	return journaling
+}
+
+// Función para leer un archivo completo con cat
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := Cat([]string{path})
+	if err != nil {
+		t.Fatalf("cat %s: %v", path, err)
+	}
+	return content
+}
+
+func TestMkfsReservesJournalingForExt3(t *testing.T) {
+	partition := newTestPartition(t, 512, "3fs")
+	superblock, start := partition.superblock(t)
+
+	entries := journalingEntries(superblock, start)
+	if entries != int64(superblock.S_inodes_count) {
+		t.Fatalf("el área del journaling tiene %d entradas, se esperaban %d", entries, superblock.S_inodes_count)
+	}
+	for _, index := range []int64{0, entries - 1} {
+		if journaling := partition.journaling(t, start, index); journaling.Size != 50 {
+			t.Errorf("la entrada %d del journaling no está inicializada", index)
+		}
+	}
+
+	// EXT2 no tiene área de journaling
+	ext2 := newTestPartition(t, 512, "2fs")
+	superblock, start = ext2.superblock(t)
+	if superblock.S_bm_inode_start != start+int64(binary.Size(Structs.Superblock{})) {
+		t.Fatalf("el bitmap de inodos de EXT2 debe ir justo después del Superblock")
+	}
+}
+
+func TestResizefsGrowsJournaling(t *testing.T) {
+	partition := newTestPartition(t, 256, "3fs")
+	if err := Mkfile("/a.txt", 300, false, ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+	before, start := partition.superblock(t)
+
+	if err := DiskManagement.ModifyPartition(partition.path, partition.name, 256, "k"); err != nil {
+		t.Fatalf("fdisk -add: %v", err)
+	}
+	if err := Resizefs(partition.id); err != nil {
+		t.Fatalf("resizefs: %v", err)
+	}
+
+	after, _ := partition.superblock(t)
+	if after.S_inodes_count <= before.S_inodes_count {
+		t.Fatalf("el sistema de archivos no creció: %d inodos", after.S_inodes_count)
+	}
+	entries := journalingEntries(after, start)
+	if entries != int64(after.S_inodes_count) || partition.journaling(t, start, entries-1).Size != 50 {
+		t.Fatalf("el área del journaling no creció con el sistema de archivos (%d entradas)", entries)
+	}
+	if content := readTestFile(t, "/a.txt"); len(content) != 300 || content[:10] != "0123456789" {
+		t.Fatalf("el archivo cambió después de resizefs: %q", content)
+	}
+}
+
+func TestResizefsKeepsExt3WithoutJournalingArea(t *testing.T) {
+	// Un EXT3 de una versión anterior calcula n igual, pero tiene el bitmap de inodos justo después
+	// del Superblock: se arma moviendo las áreas de un EXT3 nuevo sobre su área de journaling
+	partition := newTestPartition(t, 256, "3fs")
+	superblock, start := partition.superblock(t)
+	shift := superblock.S_bm_inode_start - (start + int64(binary.Size(Structs.Superblock{})))
+	end := superblock.S_block_start + int64(superblock.S_blocks_count)*int64(superblock.S_block_size)
+
+	file, err := Utilities.OpenFile(partition.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Utilities.MoveBytes(file, superblock.S_bm_inode_start, superblock.S_bm_inode_start-shift, end-superblock.S_bm_inode_start); err != nil {
+		t.Fatal(err)
+	}
+	superblock.S_bm_inode_start -= shift
+	superblock.S_bm_block_start -= shift
+	superblock.S_inode_start -= shift
+	superblock.S_block_start -= shift
+	if err := Utilities.WriteObject(file, superblock, start); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if err := Mkfile("/a.txt", 300, false, ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+
+	if err := DiskManagement.ModifyPartition(partition.path, partition.name, 256, "k"); err != nil {
+		t.Fatalf("fdisk -add: %v", err)
+	}
+	if err := Resizefs(partition.id); err != nil {
+		t.Fatalf("resizefs: %v", err)
+	}
+
+	after, _ := partition.superblock(t)
+	if after.S_inodes_count <= superblock.S_inodes_count {
+		t.Fatalf("el sistema de archivos no creció: %d inodos", after.S_inodes_count)
+	}
+	if after.S_bm_inode_start != superblock.S_bm_inode_start || journalingEntries(after, start) != 0 {
+		t.Fatalf("resizefs agregó un área de journaling que el sistema de archivos no tenía")
+	}
+	if content := readTestFile(t, "/a.txt"); len(content) != 300 || content[:10] != "0123456789" {
+		t.Fatalf("el archivo cambió después de resizefs: %q", content)
+	}
+}
+
+// Función para montar una partición del disco de prueba como la única con sesión de root
+func (p *testPartition) remount(t *testing.T, name string) {
+	t.Helper()
+	DiskManagement.CleanMountedPartitions()
+	p.name = name
+	p.id = ""
+	DiskManagement.Mount(p.path, name)
+	for _, mounted := range DiskManagement.GetMountedPartitions() {
+		for _, candidate := range mounted {
+			if candidate.Path == p.path && candidate.Name == name {
+				p.id = candidate.ID
+			}
+		}
+	}
+	if p.id == "" {
+		t.Fatalf("no se pudo montar la partición %s", name)
+	}
+	DiskManagement.MarkPartitionAsLoggedIn(p.id, "root", 1, 1)
+}
+
+func TestDumppartLoadpartRoundTrip(t *testing.T) {
+	partition := newTestPartition(t, 256, "3fs")
+	if err := Mkfile("/a.txt", 300, false, ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+	if err := DiskManagement.Fdisk(256, partition.path, "p2", "k", "p", ""); err != nil {
+		t.Fatalf("fdisk p2: %v", err)
+	}
+
+	image := filepath.Join(t.TempDir(), "Imagen.bin")
+	if err := DiskManagement.DumpPartition(partition.path, partition.name, "", image); err != nil {
+		t.Fatalf("dumppart: %v", err)
+	}
+
+	// La imagen guarda las posiciones del Superblock relativas al inicio de la partición
+	file, err := os.Open(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var superblock Structs.Superblock
+	err = binary.Read(file, binary.LittleEndian, &superblock)
+	file.Close()
+	if err != nil || superblock.S_magic != 0xEF53 {
+		t.Fatal("la imagen no empieza con el Superblock")
+	}
+	if superblock.S_bm_inode_start != int64(binary.Size(Structs.Superblock{}))+int64(superblock.S_inodes_count)*int64(binary.Size(Structs.Journaling{})) {
+		t.Fatalf("el bitmap de inodos de la imagen inicia en %d", superblock.S_bm_inode_start)
+	}
+
+	// Cargar la imagen en otra posición del disco ajusta el Superblock a la partición destino
+	if err := DiskManagement.LoadPartition(partition.path, "p2", image); err != nil {
+		t.Fatalf("loadpart: %v", err)
+	}
+	partition.remount(t, "p2")
+	if loaded, start := partition.superblock(t); journalingEntries(loaded, start) != int64(superblock.S_inodes_count) {
+		t.Fatal("la partición cargada perdió su área de journaling")
+	}
+	if content := readTestFile(t, "/a.txt"); len(content) != 300 || content[:10] != "0123456789" {
+		t.Fatalf("el archivo cambió después de loadpart: %q", content)
+	}
+}
+
+// Función para abrir el sistema de archivos de la sesión en las pruebas
+func openTestSession(t *testing.T) *fileSystemSession {
+	t.Helper()
+	s, err := openSessionFileSystem()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// Función para leer el inodo de una ruta de la partición con la sesión iniciada
+func readTestInode(t *testing.T, path string) Structs.Inode {
+	t.Helper()
+	s := openTestSession(t)
+	defer s.Close()
+	index, err := s.resolvePath(path)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	inode, err := s.readInode(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inode
+}
+
+// Función para llenar los 12 bloques directos de una carpeta nueva (48 entradas menos . y ..)
+func fillTestFolder(t *testing.T, path string) {
+	t.Helper()
+	if err := Mkdir(path, false); err != nil {
+		t.Fatalf("mkdir %s: %v", path, err)
+	}
+	for i := 0; i < directBlocks*4-2; i++ {
+		if err := Mkfile(fmt.Sprintf("%s/f%d", path, i), 0, false, ""); err != nil {
+			t.Fatalf("mkfile %d: %v", i, err)
+		}
+	}
+	if inode := readTestInode(t, path); inode.I_block[directBlocks-1] == -1 || inode.I_block[directBlocks] != -1 {
+		t.Fatalf("la carpeta no quedó con sus bloques directos llenos: %v", inode.I_block)
+	}
+}
+
+func TestFolderGrowsIntoIndirectBlocks(t *testing.T) {
+	newTestPartition(t, 512, "2fs")
+	fillTestFolder(t, "/d")
+
+	// Las entradas 49 en adelante van en Folderblocks del apuntador indirecto simple
+	for i := 0; i < 10; i++ {
+		if err := Mkdir(fmt.Sprintf("/d/c%d", i), false); err != nil {
+			t.Fatalf("mkdir %d: %v", i, err)
+		}
+	}
+	if err := Mkfile("/d/c9/a.txt", 100, false, ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+	if inode := readTestInode(t, "/d"); inode.I_block[directBlocks] == -1 {
+		t.Fatal("la carpeta no usó el apuntador indirecto simple")
+	}
+	for _, path := range []string{"/d/f0", "/d/f45", "/d/c0", "/d/c9"} {
+		readTestInode(t, path)
+	}
+	if content := readTestFile(t, "/d/c9/a.txt"); len(content) != 100 {
+		t.Fatalf("contenido inesperado: %q", content)
+	}
+}
+
+func TestCreateWithoutSpaceLeavesNoBlocksReserved(t *testing.T) {
+	partition := newTestPartition(t, 512, "2fs")
+	fillTestFolder(t, "/d")
+
+	// Dejar dos bloques libres: alcanzan para que la carpeta llena crezca (un Folderblock y un Pointerblock),
+	// pero no para el Folderblock de una carpeta nueva ni para el Fileblock de un archivo
+	s := openTestSession(t)
+	for s.superblock.S_free_blocks_count > 2 {
+		if _, err := s.allocateBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+	before, _ := partition.superblock(t)
+
+	if err := Mkdir("/d/c", false); err == nil {
+		t.Fatal("se esperaba un error por falta de espacio en mkdir")
+	}
+	if err := Mkfile("/d/a.txt", 10, false, ""); err == nil {
+		t.Fatal("se esperaba un error por falta de espacio en mkfile")
+	}
+	after, _ := partition.superblock(t)
+	if after.S_free_blocks_count != before.S_free_blocks_count || after.S_free_inodes_count != before.S_free_inodes_count {
+		t.Fatalf("se reservaron bloques o inodos: %d/%d bloques, %d/%d inodos libres",
+			after.S_free_blocks_count, before.S_free_blocks_count, after.S_free_inodes_count, before.S_free_inodes_count)
+	}
+	if inode := readTestInode(t, "/d"); inode.I_block[directBlocks] != -1 {
+		t.Fatal("la carpeta creció aunque no se pudo crear la entrada")
+	}
+
+	// Un archivo vacío solo necesita los dos bloques de la carpeta
+	if err := Mkfile("/d/a.txt", 0, false, ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+	if after, _ = partition.superblock(t); after.S_free_blocks_count != 0 {
+		t.Fatalf("quedaron %d bloques libres, se esperaban 0", after.S_free_blocks_count)
+	}
+}
+
+func TestRecoverDeletedFormattedPartition(t *testing.T) {
+	partition := newTestPartition(t, 256, "2fs")
+	if err := Mkfile("/a.txt", 300, false, ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+	_, start := partition.superblock(t)
+	DiskManagement.CleanMountedPartitions()
+	DiskManagement.DeletePartition(partition.path, partition.name, "fast")
+
+	proposals, err := DiskManagement.RecoverPartitions(partition.path, true)
+	if err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+	if len(proposals) != 1 || proposals[0].Type != "p" || proposals[0].Start != start || proposals[0].Source != "ext2" {
+		t.Fatalf("propuestas inesperadas: %+v", proposals)
+	}
+
+	partition.remount(t, proposals[0].Name)
+	if content := readTestFile(t, "/a.txt"); len(content) != 300 || content[:10] != "0123456789" {
+		t.Fatalf("el archivo de la partición recuperada cambió: %q", content)
+	}
+}
+
+// Función para generar el contenido que mkfile escribe para un tamaño (los dígitos 0123456789 repetidos)
+func testDigits(size int) string {
+	return strings.Repeat("0123456789", size/10+1)[:size]
+}
+
+// Función para reemplazar el contenido de un archivo de la partición de prueba
+func (p testPartition) writeFile(t *testing.T, path string, data string) {
+	t.Helper()
+	s := openTestSession(t)
+	index, err := s.resolvePath(path)
+	s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, start := p.superblock(t)
+	file, err := Utilities.OpenFile(p.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := WriteInodeData(file, start, index, []byte(data)); err != nil {
+		t.Fatalf("escribir %s: %v", path, err)
+	}
+}
+
+func TestFileDataThroughIndirectBlocks(t *testing.T) {
+	partition := newTestPartition(t, 512, "2fs")
+
+	// Tamaños que terminan en los bloques directos, el indirecto simple, el doble y el triple
+	single := directBlocks + pointersPerBlock
+	double := single + pointersPerBlock*pointersPerBlock
+	sizes := []int{directBlocks, single, double, double + 3}
+
+	// Los archivos se crean vacíos primero para no contar los bloques que agregue la carpeta raíz
+	for i := range sizes {
+		if err := Mkfile(fmt.Sprintf("/f%d.txt", i), 0, false, ""); err != nil {
+			t.Fatalf("mkfile: %v", err)
+		}
+	}
+	before, _ := partition.superblock(t)
+	blockSize := int(before.S_block_size)
+
+	for i, blocks := range sizes {
+		size := blocks*blockSize - 1
+		path := fmt.Sprintf("/f%d.txt", i)
+		partition.writeFile(t, path, testDigits(size))
+		if content := readTestFile(t, path); content != testDigits(size) {
+			t.Fatalf("%s: el contenido de %d bytes no se leyó igual", path, size)
+		}
+
+		after, _ := partition.superblock(t)
+		expected := int32(blocks) + pointerBlocksFor(int32(blocks))
+		if used := before.S_free_blocks_count - after.S_free_blocks_count; used != expected {
+			t.Fatalf("%s usó %d bloques, se esperaban %d", path, used, expected)
+		}
+		before = after
+	}
+	if inode := readTestInode(t, "/f3.txt"); inode.I_block[directBlocks+2] == -1 {
+		t.Fatal("el archivo más grande no usó el apuntador indirecto triple")
+	}
+
+	// Reescribir el archivo más pequeño libera los bloques y Pointerblocks que sobran
+	partition.writeFile(t, "/f3.txt", testDigits(100))
+	if content := readTestFile(t, "/f3.txt"); content != testDigits(100) {
+		t.Fatalf("contenido después de reescribir: %q", content)
+	}
+	after, _ := partition.superblock(t)
+	blocks := int32(sizes[3])
+	if freed := after.S_free_blocks_count - before.S_free_blocks_count; freed != blocks+pointerBlocksFor(blocks)-2 {
+		t.Fatalf("se liberaron %d bloques, se esperaban %d", freed, blocks+pointerBlocksFor(blocks)-2)
+	}
+	if inode := readTestInode(t, "/f3.txt"); inode.I_block[directBlocks] != -1 || inode.I_block[directBlocks+1] != -1 || inode.I_block[directBlocks+2] != -1 {
+		t.Fatalf("quedaron apuntadores indirectos: %v", inode.I_block)
+	}
+
+	// Los demás archivos no cambiaron
+	if content := readTestFile(t, "/f2.txt"); content != testDigits(double*blockSize-1) {
+		t.Fatal("el contenido de /f2.txt cambió al reescribir /f3.txt")
+	}
+}
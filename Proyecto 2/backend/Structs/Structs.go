@@ -4,8 +4,12 @@ import (
 	"fmt"
 )
 
+// Versión actual del formato en disco (tamaños y desplazamientos de 64 bits)
+const FormatVersion int32 = 2
+
 type MRB struct {
-	MbrSize      int32    // 4 bytes //int32 va desde -2,147,483,648 hasta 2,147,483,647.
+	Version      int32    // 4 bytes, versión del formato en disco (FormatVersion)
+	MbrSize      int64    // 8 bytes //int64 permite discos de más de 2 GiB
 	CreationDate [10]byte // 10 bytes
 	Signature    int32    // 4 bytes
 	Fit          [1]byte  // 1 byte
@@ -13,7 +17,7 @@ type MRB struct {
 }
 
 func PrintMBR(data MRB) {
-	fmt.Println(fmt.Sprintf("CreationDate: %s, fit: %s, size: %d", string(data.CreationDate[:]), string(data.Fit[:]), data.MbrSize))
+	fmt.Println(fmt.Sprintf("Version: %d, CreationDate: %s, fit: %s, size: %d", data.Version, string(data.CreationDate[:]), string(data.Fit[:]), data.MbrSize))
 	for i := 0; i < 4; i++ {
 		PrintPartition(data.Partitions[i])
 	}
@@ -23,11 +27,11 @@ type Partition struct {
 	Status      [1]byte
 	Type        [1]byte
 	Fit         [1]byte
-	Start       int32
-	Size        int32
+	Start       int64
+	Size        int64
 	Name        [16]byte
 	Correlative int32
-	Id          [4]byte
+	Id          [16]byte // ID de montaje (prefijo + correlativo + letra del disco)
 }
 
 func PrintPartition(data Partition) {
@@ -37,20 +41,59 @@ func PrintPartition(data Partition) {
 type EBR struct {
 	PartMount byte
 	PartFit   byte
-	PartStart int32
-	PartSize  int32
-	PartNext  int32
+	PartStart int64
+	PartSize  int64
+	PartNext  int64
 	PartName  [16]byte
+	PartId    [16]byte // ID de montaje de la partición lógica
 }
 
 func PrintEBR(data EBR) {
-	fmt.Println(fmt.Sprintf("Name: %s, fit: %c, start: %d, size: %d, next: %d, mount: %c",
+	fmt.Println(fmt.Sprintf("Name: %s, fit: %c, start: %d, size: %d, next: %d, mount: %c, id: %s",
 		string(data.PartName[:]),
 		data.PartFit,
 		data.PartStart,
 		data.PartSize,
 		data.PartNext,
-		data.PartMount))
+		data.PartMount,
+		string(data.PartId[:])))
+}
+
+//Estructuras relacionadas a GPT
+
+// Encabezado GPT: se guarda justo después de un MBR protector cuya primera partición es de tipo 'g'
+type GPTHeader struct {
+	Signature    [8]byte  // "EFI PART"
+	DiskGUID     [16]byte // Identificador único del disco
+	DiskSize     int64    // Tamaño del disco
+	EntriesStart int64    // Inicio del arreglo de entradas
+	EntryCount   int32    // Cantidad de entradas del arreglo
+	EntrySize    int32    // Tamaño de cada entrada
+	FirstUsable  int64    // Primer byte disponible para particiones (después de las entradas)
+	LastUsable   int64    // Final del área disponible para particiones (exclusivo)
+}
+
+// Entrada del arreglo de particiones GPT (solo particiones primarias)
+type GPTEntry struct {
+	Status      [1]byte
+	Type        [1]byte
+	Fit         [1]byte
+	GUID        [16]byte // Identificador único de la partición
+	Start       int64
+	Size        int64
+	Name        [36]byte
+	Correlative int32
+	Id          [16]byte // ID de montaje (prefijo + correlativo + letra del disco)
+}
+
+func PrintGPTHeader(data GPTHeader) {
+	fmt.Println(fmt.Sprintf("Signature: %s, GUID: %x, size: %d, entries: %d (inicio %d), usable: %d - %d",
+		string(data.Signature[:]), data.DiskGUID, data.DiskSize, data.EntryCount, data.EntriesStart, data.FirstUsable, data.LastUsable))
+}
+
+func PrintGPTEntry(data GPTEntry) {
+	fmt.Println(fmt.Sprintf("Name: %s, type: %s, start: %d, size: %d, status: %s, id: %s, GUID: %x",
+		string(data.Name[:]), string(data.Type[:]), data.Start, data.Size, string(data.Status[:]), string(data.Id[:]), data.GUID))
 }
 
 //Estructuras relacionadas a EXT2
@@ -69,10 +112,10 @@ type Superblock struct {
 	S_block_size        int32    // Tamaño del bloque
 	S_fist_ino          int32    // Primer inodo libre (dirección del inodo)
 	S_first_blo         int32    // Primer bloque libre (dirección del inodo)
-	S_bm_inode_start    int32    // Guardará el inicio del bitmap de inodos
-	S_bm_block_start    int32    // Guardará el inicio del bitmap de bloques
-	S_inode_start       int32    // Guardará el inicio de la tabla de inodos
-	S_block_start       int32    // Guardará el inicio de la tabla de bloques
+	S_bm_inode_start    int64    // Guardará el inicio del bitmap de inodos
+	S_bm_block_start    int64    // Guardará el inicio del bitmap de bloques
+	S_inode_start       int64    // Guardará el inicio de la tabla de inodos
+	S_block_start       int64    // Guardará el inicio de la tabla de bloques
 }
 
 func PrintSuperblock(sb Superblock) {
@@ -176,3 +219,55 @@ type Journaling struct {
 	Ultimo    int32
 	Contenido [50]Content_J
 }
+
+//Estructuras del formato anterior (versión 1, tamaños y desplazamientos de 32 bits)
+//Solo se usan para leer discos antiguos y migrarlos con el comando migrate.
+//Este formato no guarda su versión en el disco (MBR de 159 bytes, Id de 4 bytes y EBR sin Id)
+
+type MRBv1 struct {
+	MbrSize      int32
+	CreationDate [10]byte
+	Signature    int32
+	Fit          [1]byte
+	Partitions   [4]PartitionV1
+}
+
+type PartitionV1 struct {
+	Status      [1]byte
+	Type        [1]byte
+	Fit         [1]byte
+	Start       int32
+	Size        int32
+	Name        [16]byte
+	Correlative int32
+	Id          [4]byte
+}
+
+type EBRv1 struct {
+	PartMount byte
+	PartFit   byte
+	PartStart int32
+	PartSize  int32
+	PartNext  int32
+	PartName  [16]byte
+}
+
+type SuperblockV1 struct {
+	S_filesystem_type   int32
+	S_inodes_count      int32
+	S_blocks_count      int32
+	S_free_blocks_count int32
+	S_free_inodes_count int32
+	S_mtime             [17]byte
+	S_umtime            [17]byte
+	S_mnt_count         int32
+	S_magic             int32
+	S_inode_size        int32
+	S_block_size        int32
+	S_fist_ino          int32
+	S_first_blo         int32
+	S_bm_inode_start    int32
+	S_bm_block_start    int32
+	S_inode_start       int32
+	S_block_start       int32
+}
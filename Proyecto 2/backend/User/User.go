@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"proyecto1/DiskManagement"
+	"proyecto1/FileSystem"
 	"proyecto1/Structs"
 	"proyecto1/Utilities"
+	"strconv"
 	"strings"
 )
 
@@ -17,33 +19,20 @@ func Login(user string, pass string, id string) (string, error) {
 	fmt.Println("Id:", id)
 
 	// Verificar si el usuario ya está logueado buscando en las particiones montadas
-	mountedPartitions := DiskManagement.GetMountedPartitions()
-	var filepath string
-	var partitionFound bool
+	mountedPartition, partitionFound := DiskManagement.GetMountedPartition(id)
 	var login bool = false
 
-	for _, partitions := range mountedPartitions {
-		for _, partition := range partitions {
-			if partition.ID == id && partition.LoggedIn { // Verifica si ya está logueado
-				fmt.Println("Ya existe un usuario logueado!")
-				return "", fmt.Errorf("Ya existe un usuario logueado en esta partición")
-			}
-			if partition.ID == id { // Encuentra la partición correcta
-				filepath = partition.Path
-				partitionFound = true
-				break
-			}
-		}
-		if partitionFound {
-			break
-		}
-	}
-
 	if !partitionFound {
 		fmt.Println("Error: No se encontró ninguna partición montada con el ID proporcionado")
 		return "", fmt.Errorf("No se encontró ninguna partición montada con el ID proporcionado")
 	}
 
+	if mountedPartition.LoggedIn { // Verifica si ya está logueado
+		fmt.Println("Ya existe un usuario logueado!")
+		return "", fmt.Errorf("Ya existe un usuario logueado en esta partición")
+	}
+	filepath := mountedPartition.Path
+
 	// Abrir archivo binario
 	file, err := Utilities.OpenFile(filepath)
 	if err != nil {
@@ -52,53 +41,37 @@ func Login(user string, pass string, id string) (string, error) {
 	}
 	defer file.Close()
 
-	var TempMBR Structs.MRB
-	// Leer el MBR desde el archivo binario
-	if err := Utilities.ReadObject(file, &TempMBR, 0); err != nil {
-		fmt.Println("Error: No se pudo leer el MBR:", err)
-		return "", fmt.Errorf("Error al leer el MBR")
-	}
-
-	// Imprimir el MBR
-	Structs.PrintMBR(TempMBR)
-	fmt.Println("-------------")
-
-	var index int = -1
-	// Iterar sobre las particiones del MBR para encontrar la correcta
-	for i := 0; i < 4; i++ {
-		if TempMBR.Partitions[i].Size != 0 {
-			if strings.Contains(string(TempMBR.Partitions[i].Id[:]), id) {
-				fmt.Println("Partition found")
-				if TempMBR.Partitions[i].Status[0] == '1' {
-					fmt.Println("Partition is mounted")
-					index = i
-				} else {
-					fmt.Println("Partition is not mounted")
-					return "", fmt.Errorf("La partición no está montada")
-				}
-				break
-			}
-		}
+	// Buscar la partición (primaria o lógica) con el ID dentro del disco
+	partition, err := DiskManagement.FindPartitionByID(file, id)
+	if err != nil {
+		fmt.Println("Partition not found:", err)
+		return "", fmt.Errorf("No se encontró la partición con el ID proporcionado")
 	}
+	fmt.Println("Partition found")
 
-	if index == -1 {
-		fmt.Println("Partition not found")
-		return "", fmt.Errorf("No se encontró la partición con el ID proporcionado")
+	if partition.Status != '1' {
+		fmt.Println("Partition is not mounted")
+		return "", fmt.Errorf("La partición no está montada")
 	}
+	fmt.Println("Partition is mounted")
 
 	var tempSuperblock Structs.Superblock
 	// Leer el Superblock desde el archivo binario
-	if err := Utilities.ReadObject(file, &tempSuperblock, int64(TempMBR.Partitions[index].Start)); err != nil {
+	if err := Utilities.ReadObject(file, &tempSuperblock, partition.Start); err != nil {
 		fmt.Println("Error: No se pudo leer el Superblock:", err)
 		return "", fmt.Errorf("Error al leer el Superblock")
 	}
 
 	// Buscar el archivo de usuarios /users.txt -> retorna índice del Inodo
 	indexInode := InitSearch("/users.txt", file, tempSuperblock)
+	if indexInode == -1 {
+		fmt.Println("Error: No se encontró el archivo users.txt")
+		return "", fmt.Errorf("No se encontró el archivo users.txt")
+	}
 
 	var crrInode Structs.Inode
 	// Leer el Inodo desde el archivo binario
-	if err := Utilities.ReadObject(file, &crrInode, int64(tempSuperblock.S_inode_start+indexInode*int32(binary.Size(Structs.Inode{})))); err != nil {
+	if err := Utilities.ReadObject(file, &crrInode, tempSuperblock.S_inode_start+int64(indexInode)*int64(binary.Size(Structs.Inode{}))); err != nil {
 		fmt.Println("Error: No se pudo leer el Inodo:", err)
 		return "", fmt.Errorf("Error al leer el Inodo")
 	}
@@ -110,24 +83,37 @@ func Login(user string, pass string, id string) (string, error) {
 	lines := strings.Split(data, "\n")
 
 	// Iterar a través de las líneas para verificar las credenciales
+	var uid, gid int32
+	var group string
 	for _, line := range lines {
 		words := strings.Split(line, ",")
 
 		if len(words) == 5 {
 			if (strings.Contains(words[3], user)) && (strings.Contains(words[4], pass)) {
 				login = true
+				uid = parseUsersID(words[0])
+				group = strings.TrimSpace(words[2])
 				break
 			}
 		}
 	}
 
+	// Buscar el ID del grupo del usuario
+	for _, line := range lines {
+		words := strings.Split(line, ",")
+		if len(words) == 3 && strings.TrimSpace(words[1]) == "G" && strings.TrimSpace(words[2]) == group {
+			gid = parseUsersID(words[0])
+			break
+		}
+	}
+
 	// Imprimir información del Inodo
 	fmt.Println("Inode", crrInode.I_block)
 
 	// Si las credenciales son correctas y marcamos como logueado
 	if login {
 		fmt.Println("Usuario logueado con éxito")
-		DiskManagement.MarkPartitionAsLoggedIn(id) // Marcar la partición como logueada
+		DiskManagement.MarkPartitionAsLoggedIn(id, user, uid, gid) // Marcar la partición como logueada con el usuario de la sesión
 		return "Inicio de sesión exitoso", nil
 	}
 
@@ -135,6 +121,15 @@ func Login(user string, pass string, id string) (string, error) {
 	return "", fmt.Errorf("Credenciales incorrectas")
 }
 
+// Función para leer el ID numérico de una línea de users.txt
+func parseUsersID(value string) int32 {
+	id, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0
+	}
+	return int32(id)
+}
+
 func InitSearch(path string, file *os.File, tempSuperblock Structs.Superblock) int32 {
 	fmt.Println("======Start BUSQUEDA INICIAL ======")
 	fmt.Println("path:", path)
@@ -151,7 +146,7 @@ func InitSearch(path string, file *os.File, tempSuperblock Structs.Superblock) i
 
 	var Inode0 Structs.Inode
 	// Read object from bin file
-	if err := Utilities.ReadObject(file, &Inode0, int64(tempSuperblock.S_inode_start)); err != nil {
+	if err := Utilities.ReadObject(file, &Inode0, tempSuperblock.S_inode_start); err != nil {
 		return -1
 	}
 
@@ -160,94 +155,69 @@ func InitSearch(path string, file *os.File, tempSuperblock Structs.Superblock) i
 	return SarchInodeByPath(StepsPath, Inode0, file, tempSuperblock)
 }
 
-// stack
-func pop(s *[]string) string {
-	lastIndex := len(*s) - 1
-	last := (*s)[lastIndex]
-	*s = (*s)[:lastIndex]
-	return last
-}
-
 func SarchInodeByPath(StepsPath []string, Inode Structs.Inode, file *os.File, tempSuperblock Structs.Superblock) int32 {
 	fmt.Println("======Start BUSQUEDA INODO POR PATH======")
-	index := int32(0)
-	SearchedName := strings.Replace(pop(&StepsPath), " ", "", -1)
+	if len(StepsPath) == 0 {
+		return -1
+	}
+	// El primer nombre de la ruta es el que se busca en esta carpeta
+	SearchedName := strings.TrimSpace(StepsPath[0])
+	StepsPath = StepsPath[1:]
 
 	fmt.Println("========== SearchedName:", SearchedName)
 
-	// Iterate over i_blocks from Inode
-	for _, block := range Inode.I_block {
-		if block != -1 {
-			if index < 13 {
-				//CASO DIRECTO
-
-				var crrFolderBlock Structs.Folderblock
-				// Read object from bin file
-				if err := Utilities.ReadObject(file, &crrFolderBlock, int64(tempSuperblock.S_block_start+block*int32(binary.Size(Structs.Folderblock{})))); err != nil {
-					return -1
-				}
-
-				for _, folder := range crrFolderBlock.B_content {
-					// fmt.Println("Folder found======")
-					fmt.Println("Folder === Name:", string(folder.B_name[:]), "B_inodo", folder.B_inodo)
-
-					if strings.Contains(string(folder.B_name[:]), SearchedName) {
-
-						fmt.Println("len(StepsPath)", len(StepsPath), "StepsPath", StepsPath)
-						if len(StepsPath) == 0 {
-							fmt.Println("Folder found======")
-							return folder.B_inodo
-						} else {
-							fmt.Println("NextInode======")
-							var NextInode Structs.Inode
-							// Read object from bin file
-							if err := Utilities.ReadObject(file, &NextInode, int64(tempSuperblock.S_inode_start+folder.B_inodo*int32(binary.Size(Structs.Inode{})))); err != nil {
-								return -1
-							}
-							return SarchInodeByPath(StepsPath, NextInode, file, tempSuperblock)
-						}
-					}
-				}
-
-			} else {
-				fmt.Print("indirectos")
+	// Recorrer los bloques de la carpeta (directos e indirectos)
+	blocks, err := FileSystem.InodeBlocks(file, tempSuperblock, Inode)
+	if err != nil {
+		fmt.Println("Error al leer los bloques de la carpeta:", err)
+		return -1
+	}
+
+	for _, block := range blocks {
+		var crrFolderBlock Structs.Folderblock
+		// Read object from bin file
+		if err := Utilities.ReadObject(file, &crrFolderBlock, tempSuperblock.S_block_start+int64(block)*int64(binary.Size(Structs.Folderblock{}))); err != nil {
+			return -1
+		}
+
+		for _, folder := range crrFolderBlock.B_content {
+			// Las entradas con B_inodo -1 se liberaron al borrar
+			if folder.B_inodo == -1 || strings.TrimRight(string(folder.B_name[:]), "\x00") != SearchedName {
+				continue
+			}
+
+			fmt.Println("len(StepsPath)", len(StepsPath), "StepsPath", StepsPath)
+			if len(StepsPath) == 0 {
+				fmt.Println("Folder found======")
+				return folder.B_inodo
 			}
+
+			fmt.Println("NextInode======")
+			var NextInode Structs.Inode
+			// Read object from bin file
+			if err := Utilities.ReadObject(file, &NextInode, tempSuperblock.S_inode_start+int64(folder.B_inodo)*int64(binary.Size(Structs.Inode{}))); err != nil {
+				return -1
+			}
+			return SarchInodeByPath(StepsPath, NextInode, file, tempSuperblock)
 		}
-		index++
 	}
 
 	fmt.Println("======End BUSQUEDA INODO POR PATH======")
-	return 0
+	return -1
 }
 
 func GetInodeFileData(Inode Structs.Inode, file *os.File, tempSuperblock Structs.Superblock) string {
 	fmt.Println("======Start CONTENIDO DEL BLOQUE======")
-	index := int32(0)
-	// define content as a string
-	var content string
-
-	// Iterate over i_blocks from Inode
-	for _, block := range Inode.I_block {
-		if block != -1 {
-			//Dentro de los directos
-			if index < 13 {
-				var crrFileBlock Structs.Fileblock
-				// Read object from bin file
-				if err := Utilities.ReadObject(file, &crrFileBlock, int64(tempSuperblock.S_block_start+block*int32(binary.Size(Structs.Fileblock{})))); err != nil {
-					return ""
-				}
-
-				content += string(crrFileBlock.B_content[:])
-
-			} else {
-				fmt.Print("indirectos")
-			}
-		}
-		index++
+
+	// Leer exactamente I_size bytes siguiendo los apuntadores directos e indirectos
+	content, err := FileSystem.ReadInodeData(file, tempSuperblock, Inode)
+	if err != nil {
+		fmt.Println("Error al leer el contenido del archivo:", err)
+		return ""
 	}
 
 	fmt.Println("======End CONTENIDO DEL BLOQUE======")
-	return content
+	return string(content)
 }
 
 // MKUSER
@@ -290,42 +260,21 @@ func MkusrCommand(path string, newUser string) error {
 	}
 	defer file.Close()
 
-	// Leer el MBR desde el archivo binario
-	var TempMBR Structs.MRB
-	if err := Utilities.ReadObject(file, &TempMBR, 0); err != nil {
-		fmt.Println("Error: No se pudo leer el MBR:", err)
-		return err
-	}
-
-	// Imprimir el MBR
-	Structs.PrintMBR(TempMBR)
-
-	var index int = -1
-	// Iterar sobre las particiones del MBR para encontrar la correcta
-	for i := 0; i < 4; i++ {
-		if TempMBR.Partitions[i].Size != 0 {
-			if strings.Contains(string(TempMBR.Partitions[i].Id[:]), partitionFound.ID) {
-				fmt.Println("Partition found")
-				if TempMBR.Partitions[i].Status[0] == '1' {
-					fmt.Println("Partition is mounted")
-					index = i
-				} else {
-					fmt.Println("Partition is not mounted")
-					return fmt.Errorf("partición no montada")
-				}
-				break
-			}
-		}
+	// Buscar la partición (primaria o lógica) logueada dentro del disco
+	partition, err := DiskManagement.FindPartitionByID(file, partitionFound.ID)
+	if err != nil {
+		fmt.Println("Error: No se encontró la partición en el disco:", err)
+		return fmt.Errorf("partición no encontrada en el disco")
 	}
 
-	if index == -1 {
-		fmt.Println("Error: No se encontró la partición en el MBR.")
-		return fmt.Errorf("partición no encontrada en el MBR")
+	if partition.Status != '1' {
+		fmt.Println("Partition is not mounted")
+		return fmt.Errorf("partición no montada")
 	}
 
 	// Aquí se realiza la lectura del Superblock desde la partición correcta
 	var tempSuperblock Structs.Superblock
-	if err := Utilities.ReadObject(file, &tempSuperblock, int64(TempMBR.Partitions[index].Start)); err != nil {
+	if err := Utilities.ReadObject(file, &tempSuperblock, partition.Start); err != nil {
 		fmt.Println("Error: No se pudo leer el Superblock:", err)
 		return err
 	}
@@ -343,7 +292,7 @@ func MkusrCommand(path string, newUser string) error {
 
 	// Leer el inodo de users.txt
 	var usersInode Structs.Inode
-	if err := Utilities.ReadObject(file, &usersInode, int64(tempSuperblock.S_inode_start+inodeIndex*int32(binary.Size(Structs.Inode{})))); err != nil {
+	if err := Utilities.ReadObject(file, &usersInode, tempSuperblock.S_inode_start+int64(inodeIndex)*int64(binary.Size(Structs.Inode{}))); err != nil {
 		return fmt.Errorf("error al leer el inodo de users.txt: %v", err)
 	}
 
@@ -360,36 +309,21 @@ func MkusrCommand(path string, newUser string) error {
 	fmt.Printf("Tamaño actual de los datos limpios en users.txt: %d bytes\n", len(cleanedData))
 
 	// Concatenar el nuevo usuario a los datos limpios
-	newData := cleanedData + "\n" + newUser
+	if cleanedData != "" && !strings.HasSuffix(cleanedData, "\n") {
+		cleanedData += "\n"
+	}
+	newData := cleanedData + newUser + "\n"
 
 	// Imprimir el contenido y tamaño de cada parte para depuración
 	fmt.Printf("Datos actuales: '%s' (%d bytes)\n", cleanedData, len(cleanedData))
 	fmt.Printf("Nuevo usuario: '%s' (%d bytes)\n", newUser, len(newUser))
 	fmt.Printf("Datos concatenados: '%s' (%d bytes)\n", newData, len(newData))
 
-	// Verificar si el nuevo contenido cabe en el bloque
-	if len(newData) > 64 {
-		fmt.Println("Advertencia: El contenido excede el tamaño del bloque, se truncará el contenido")
-		newData = newData[:64] // Truncar el contenido a 64 bytes
-	}
-
-	// Guardar los datos actualizados de nuevo en el bloque de archivo correspondiente
-	var fileBlock Structs.Fileblock
-	copy(fileBlock.B_content[:], newData)
-
-	// Escribir el bloque actualizado de vuelta en el archivo
-	if err := Utilities.WriteObject(file, fileBlock, int64(tempSuperblock.S_block_start+usersInode.I_block[0]*int32(binary.Size(Structs.Fileblock{})))); err != nil {
+	// Guardar el contenido actualizado; el archivo crece a más bloques si hace falta
+	if err := FileSystem.WriteInodeData(file, partition.Start, inodeIndex, []byte(newData)); err != nil {
 		return fmt.Errorf("error al escribir en users.txt: %v", err)
 	}
 
-	// Verificar el contenido del bloque después de la escritura
-	fmt.Println("====== Verificación del bloque de archivo ======")
-	var updatedFileBlock Structs.Fileblock
-	if err := Utilities.ReadObject(file, &updatedFileBlock, int64(tempSuperblock.S_block_start+usersInode.I_block[0]*int32(binary.Size(Structs.Fileblock{})))); err != nil {
-		return fmt.Errorf("error al leer el bloque actualizado: %v", err)
-	}
-	Structs.PrintFileblock(updatedFileBlock) // Imprimir el bloque de archivo actualizado
-
 	fmt.Println("Usuario creado con éxito:", newUser)
 
 	return nil
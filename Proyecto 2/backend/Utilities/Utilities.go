@@ -3,9 +3,11 @@ package Utilities
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"proyecto1/Structs"
+	"sort"
 	"strings"
 )
 
@@ -63,40 +65,121 @@ func ReadObject(file *os.File, data interface{}, position int64) error {
 }
 
 // Función para llenar el espacio con ceros (\0)
-func FillWithZeros(file *os.File, start int32, size int32) error {
-	// Posiciona el archivo al inicio del área que debe ser llenada
-	file.Seek(int64(start), 0)
+func FillWithZeros(file *os.File, start int64, size int64) error {
+	// Escribir los ceros por bloques de 1MB para no reservar en memoria toda el área
+	chunkSize := int64(1024 * 1024)
+	buffer := make([]byte, chunkSize)
+
+	for written := int64(0); written < size; {
+		length := chunkSize
+		if size-written < length {
+			length = size - written
+		}
+		if _, err := file.WriteAt(buffer[:length], start+written); err != nil {
+			fmt.Println("Error al llenar el espacio con ceros:", err)
+			return err
+		}
+		written += length
+	}
 
-	// Crear un buffer lleno de ceros
-	buffer := make([]byte, size)
+	fmt.Println("Espacio llenado con ceros desde el byte", start, "por", size, "bytes.")
+	return nil
+}
 
-	// Escribir los ceros en el archivo
-	_, err := file.Write(buffer)
-	if err != nil {
-		fmt.Println("Error al llenar el espacio con ceros:", err)
-		return err
+// Función para convertir un tamaño a bytes según la unidad (b/k/m), validando que no se desborde
+func ConvertToBytes(size int, unit string) (int64, error) {
+	var multiplier int64 = 1
+	if unit == "k" {
+		multiplier = 1024
+	} else if unit == "m" {
+		multiplier = 1024 * 1024
+	}
+
+	if int64(size) > math.MaxInt64/multiplier || int64(size) < math.MinInt64/multiplier {
+		return 0, fmt.Errorf("el tamaño %d%s excede el máximo representable", size, unit)
+	}
+	return int64(size) * multiplier, nil
+}
+
+// Función para mover un área del archivo a otra posición, aunque ambas áreas se traslapen
+func MoveBytes(file *os.File, src int64, dst int64, size int64) error {
+	if src == dst || size <= 0 {
+		return nil
+	}
+
+	chunkSize := int64(1024 * 1024) // Bloques de 1MB
+	buffer := make([]byte, chunkSize)
+
+	for copied := int64(0); copied < size; {
+		length := chunkSize
+		if size-copied < length {
+			length = size - copied
+		}
+
+		// Hacia el inicio se copia de principio a fin; hacia el final, de fin a principio,
+		// para no sobrescribir datos que todavía no se han copiado
+		offset := copied
+		if dst > src {
+			offset = size - copied - length
+		}
+
+		if _, err := file.ReadAt(buffer[:length], src+offset); err != nil {
+			return fmt.Errorf("Error al leer los datos a mover: %v", err)
+		}
+		if _, err := file.WriteAt(buffer[:length], dst+offset); err != nil {
+			return fmt.Errorf("Error al escribir los datos movidos: %v", err)
+		}
+		copied += length
 	}
 
-	fmt.Println("Espacio llenado con ceros desde el byte", start, "por", size, "bytes.")
 	return nil
 }
 
-// Función para verificar que un bloque del archivo esté lleno de ceros
-func VerifyZeros(file *os.File, start int32, size int32) {
-	zeros := make([]byte, size)
-	_, err := file.ReadAt(zeros, int64(start))
-	if err != nil {
-		fmt.Println("Error al leer la sección eliminada:", err)
-		return
+// Función para copiar un área de un archivo a otro archivo (o a otra posición del mismo, sin traslape)
+func CopyBytes(src *os.File, srcStart int64, dst *os.File, dstStart int64, size int64) error {
+	chunkSize := int64(1024 * 1024) // Bloques de 1MB
+	buffer := make([]byte, chunkSize)
+
+	for copied := int64(0); copied < size; {
+		length := chunkSize
+		if size-copied < length {
+			length = size - copied
+		}
+		if _, err := src.ReadAt(buffer[:length], srcStart+copied); err != nil {
+			return fmt.Errorf("Error al leer los datos a copiar: %v", err)
+		}
+		if _, err := dst.WriteAt(buffer[:length], dstStart+copied); err != nil {
+			return fmt.Errorf("Error al escribir los datos copiados: %v", err)
+		}
+		copied += length
 	}
 
+	return nil
+}
+
+// Función para verificar que un bloque del archivo esté lleno de ceros
+func VerifyZeros(file *os.File, start int64, size int64) {
+	chunkSize := int64(1024 * 1024) // Bloques de 1MB
+	buffer := make([]byte, chunkSize)
+
 	// Verificar si todos los bytes leídos son ceros
 	isZeroFilled := true
-	for _, b := range zeros {
-		if b != 0 {
-			isZeroFilled = false
-			break
+	for checked := int64(0); checked < size && isZeroFilled; {
+		length := chunkSize
+		if size-checked < length {
+			length = size - checked
+		}
+		if _, err := file.ReadAt(buffer[:length], start+checked); err != nil {
+			fmt.Println("Error al leer la sección eliminada:", err)
+			return
 		}
+		for _, b := range buffer[:length] {
+			if b != 0 {
+				isZeroFilled = false
+				break
+			}
+		}
+		checked += length
 	}
 
 	if isZeroFilled {
@@ -106,6 +189,15 @@ func VerifyZeros(file *os.File, start int32, size int32) {
 	}
 }
 
+// Función para describir el tamaño lógico de un disco y lo que ocupa realmente en el host
+func diskUsageLabel(file *os.File, logicalSize int64) string {
+	usage, err := HostUsage(file)
+	if err != nil {
+		return fmt.Sprintf("Tamaño lógico: %d bytes", logicalSize)
+	}
+	return fmt.Sprintf("Tamaño lógico: %d bytes\\nUso en el host: %d bytes", logicalSize, usage)
+}
+
 // Función para generar el reporte del MBR y los EBRs en formato Graphviz y guardarlo en un archivo .dot
 func GenerateMBRReport(mbr Structs.MRB, ebrs []Structs.EBR, outputPath string, file *os.File) error {
 	// Crear la carpeta si no existe
@@ -128,8 +220,8 @@ func GenerateMBRReport(mbr Structs.MRB, ebrs []Structs.EBR, outputPath string, f
 	content += "\tnode [fillcolor=lightyellow style=filled]\n"
 
 	// Subgrafo del MBR
-	content += fmt.Sprintf("\tsubgraph cluster_MBR {\n\t\tcolor=lightgrey fillcolor=lightblue label=\"MBR\nTamaño: %d\nFecha Creación: %s\nDisk Signature: %d\" style=filled\n",
-		mbr.MbrSize, string(mbr.CreationDate[:]), mbr.Signature)
+	content += fmt.Sprintf("\tsubgraph cluster_MBR {\n\t\tcolor=lightgrey fillcolor=lightblue label=\"MBR\n%s\nFecha Creación: %s\nDisk Signature: %d\" style=filled\n",
+		diskUsageLabel(file, mbr.MbrSize), string(mbr.CreationDate[:]), mbr.Signature)
 
 	// Recorrer las particiones del MBR en orden
 	lastPartId := ""
@@ -151,26 +243,8 @@ func GenerateMBRReport(mbr Structs.MRB, ebrs []Structs.EBR, outputPath string, f
 			if string(part.Type[:]) == "e" {
 				content += fmt.Sprintf("\tsubgraph cluster_EBR%d {\n\t\tcolor=black fillcolor=lightpink label=\"Partición Extendida %d\" style=dashed\n", i+1, i+1)
 
-				// Recolectamos todos los EBRs en orden
-				ebrPos := part.Start
-				var ebrList []Structs.EBR
-				for {
-					var ebr Structs.EBR
-					err := ReadObject(file, &ebr, int64(ebrPos)) // Asegúrate de que la función ReadObject proviene de Utilities
-					if err != nil {
-						fmt.Println("Error al leer EBR:", err)
-						break
-					}
-					ebrList = append(ebrList, ebr)
-
-					// Si no hay más EBRs, salir del bucle
-					if ebr.PartNext == -1 {
-						break
-					}
-
-					// Mover a la siguiente posición de EBR
-					ebrPos = ebr.PartNext
-				}
+				// Los EBRs ya vienen leídos en el orden de la cadena (sin ciclos)
+				ebrList := ebrs
 
 				// Ahora agregamos los EBRs en orden correcto
 				lastEbrId := ""
@@ -207,7 +281,7 @@ func GenerateMBRReport(mbr Structs.MRB, ebrs []Structs.EBR, outputPath string, f
 }
 
 // Función para generar el reporte DISK en formato .dot
-func GenerateDiskReport(mbr Structs.MRB, ebrs []Structs.EBR, outputPath string, file *os.File, totalDiskSize int32) error {
+func GenerateDiskReport(mbr Structs.MRB, ebrs []Structs.EBR, outputPath string, file *os.File, totalDiskSize int64) error {
 	// Crear la carpeta si no existe
 	reportsDir := filepath.Dir(outputPath)
 	err := os.MkdirAll(reportsDir, os.ModePerm)
@@ -228,18 +302,19 @@ func GenerateDiskReport(mbr Structs.MRB, ebrs []Structs.EBR, outputPath string,
 	content += "\tnode [shape=none];\n"
 	content += "\tgraph [splines=false];\n"
 	content += "\tsubgraph cluster_disk {\n"
-	content += "\t\tlabel=\"Disco1.dsk\";\n"
+	content += fmt.Sprintf("\t\tlabel=\"Disco1.dsk\\n%s\";\n", diskUsageLabel(file, totalDiskSize))
 	content += "\t\tstyle=rounded;\n"
 	content += "\t\tcolor=black;\n"
 
 	// Iniciar tabla para las particiones
 	content += "\t\ttable [label=<\n\t\t\t<TABLE BORDER=\"1\" CELLBORDER=\"1\" CELLSPACING=\"0\" CELLPADDING=\"10\">\n"
 	content += "\t\t\t<TR>\n"
-	content += "\t\t\t<TD>MBR (159 bytes)</TD>\n"
+	mbrSize := int64(binary.Size(mbr))
+	content += fmt.Sprintf("\t\t\t<TD>MBR (%d bytes)</TD>\n", mbrSize)
 
 	// Variables para el porcentaje y espacio libre
-	var usedSpace int32 = 159 // Tamaño del MBR en bytes
-	var freeSpace int32 = totalDiskSize - usedSpace
+	var usedSpace int64 = mbrSize // Tamaño del MBR en bytes
+	var freeSpace int64 = totalDiskSize - usedSpace
 
 	for i := 0; i < 4; i++ {
 		part := mbr.Partitions[i]
@@ -257,10 +332,15 @@ func GenerateDiskReport(mbr Structs.MRB, ebrs []Structs.EBR, outputPath string,
 
 				// Leer los EBRs y agregar las particiones lógicas
 				content += "\t\t\t\t<TR>\n"
+				ebrSize := int64(binary.Size(Structs.EBR{}))
 				for _, ebr := range ebrs {
+					if ebr.PartSize <= 0 {
+						continue // EBR vacío, no representa una partición lógica
+					}
+					logicalName := strings.TrimRight(string(ebr.PartName[:]), "\x00")
 					logicalPercentage := float64(ebr.PartSize) / float64(totalDiskSize) * 100
-					content += fmt.Sprintf("\t\t\t\t<TD>EBR (32 bytes)</TD>\n\t\t\t\t<TD>Lógica<br/>%.2f%% del disco</TD>\n", logicalPercentage)
-					usedSpace += ebr.PartSize + 32 // Añadir el tamaño de la partición lógica y el EBR
+					content += fmt.Sprintf("\t\t\t\t<TD>EBR (%d bytes)</TD>\n\t\t\t\t<TD>Lógica<br/>%s<br/>%.2f%% del disco</TD>\n", ebrSize, logicalName, logicalPercentage)
+					usedSpace += ebr.PartSize + ebrSize // Añadir el tamaño de la partición lógica y el EBR
 				}
 				content += "\t\t\t\t</TR>\n"
 				content += "\t\t\t\t</TABLE>\n"
@@ -289,3 +369,131 @@ func GenerateDiskReport(mbr Structs.MRB, ebrs []Structs.EBR, outputPath string,
 	fmt.Println("Reporte DISK generado exitosamente en:", dotFilePath)
 	return nil
 }
+
+// Función para generar el reporte de una tabla GPT (MBR protector, encabezado y entradas) en formato .dot
+func GenerateGPTReport(mbr Structs.MRB, header Structs.GPTHeader, entries []Structs.GPTEntry, outputPath string, file *os.File) error {
+	// Crear la carpeta si no existe
+	reportsDir := filepath.Dir(outputPath)
+	err := os.MkdirAll(reportsDir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("Error al crear la carpeta de reportes: %v", err)
+	}
+
+	// Crear el archivo .dot donde se generará el reporte
+	dotFilePath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".dot"
+	fileDot, err := os.Create(dotFilePath)
+	if err != nil {
+		return fmt.Errorf("Error al crear el archivo .dot de reporte: %v", err)
+	}
+	defer fileDot.Close()
+
+	// Iniciar el contenido del archivo en formato Graphviz (.dot)
+	content := "digraph G {\n"
+	content += "\tnode [fillcolor=lightyellow style=filled]\n"
+
+	// Subgrafo de la tabla GPT
+	content += fmt.Sprintf("\tsubgraph cluster_GPT {\n\t\tcolor=lightgrey fillcolor=lightblue label=\"GPT\n%s\nFecha Creación: %s\nDisk Signature: %d\nDisk GUID: %x\nEntradas: %d\" style=filled\n",
+		diskUsageLabel(file, header.DiskSize), string(mbr.CreationDate[:]), mbr.Signature, header.DiskGUID, header.EntryCount)
+
+	// Recorrer las entradas usadas en orden
+	lastPartId := ""
+	for i, entry := range entries {
+		if entry.Size <= 0 {
+			continue
+		}
+		entryName := strings.TrimRight(string(entry.Name[:]), "\x00")
+		partId := fmt.Sprintf("PART%d", i+1)
+		content += fmt.Sprintf("\t\t%s [label=\"Entrada %d\nStatus: %s\nType: %s\nFit: %s\nStart: %d\nSize: %d\nName: %s\nGUID: %x\" fillcolor=green shape=box style=filled]\n",
+			partId, i+1, string(entry.Status[:]), string(entry.Type[:]), string(entry.Fit[:]), entry.Start, entry.Size, entryName, entry.GUID)
+
+		// Conectar la entrada actual con la anterior de manera invisible para mantener el orden
+		if lastPartId != "" {
+			content += fmt.Sprintf("\t\t%s -> %s [style=invis]\n", lastPartId, partId)
+		}
+		lastPartId = partId
+	}
+
+	content += "\t}\n" // Cerrar el subgrafo de la tabla GPT
+
+	content += "}\n" // Cerrar el grafo principal
+
+	// Escribir el contenido en el archivo .dot
+	_, err = fileDot.WriteString(content)
+	if err != nil {
+		return fmt.Errorf("Error al escribir en el archivo .dot: %v", err)
+	}
+
+	fmt.Println("Reporte GPT generado exitosamente en:", dotFilePath)
+	return nil
+}
+
+// Función para generar el reporte DISK de un disco GPT en formato .dot
+func GenerateGPTDiskReport(header Structs.GPTHeader, entries []Structs.GPTEntry, outputPath string, file *os.File) error {
+	// Crear la carpeta si no existe
+	reportsDir := filepath.Dir(outputPath)
+	err := os.MkdirAll(reportsDir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("Error al crear la carpeta de reportes: %v", err)
+	}
+
+	// Crear el archivo .dot donde se generara el reporte
+	dotFilePath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".dot"
+	fileDot, err := os.Create(dotFilePath)
+	if err != nil {
+		return fmt.Errorf("Error al crear el archivo .dot de reporte: %v", err)
+	}
+	defer fileDot.Close()
+
+	// Iniciar el contenido del archivo en formato Graphviz (.dot)
+	content := "digraph G {\n"
+	content += "\tnode [shape=none];\n"
+	content += "\tgraph [splines=false];\n"
+	content += "\tsubgraph cluster_disk {\n"
+	content += fmt.Sprintf("\t\tlabel=\"Disco1.dsk\\n%s\";\n", diskUsageLabel(file, header.DiskSize))
+	content += "\t\tstyle=rounded;\n"
+	content += "\t\tcolor=black;\n"
+
+	// Iniciar tabla para las particiones
+	content += "\t\ttable [label=<\n\t\t\t<TABLE BORDER=\"1\" CELLBORDER=\"1\" CELLSPACING=\"0\" CELLPADDING=\"10\">\n"
+	content += "\t\t\t<TR>\n"
+	content += fmt.Sprintf("\t\t\t<TD>GPT (%d bytes)</TD>\n", header.FirstUsable)
+
+	// Ordenar las entradas por su posición física, intercalando los espacios libres
+	var used []Structs.GPTEntry
+	for _, entry := range entries {
+		if entry.Size > 0 {
+			used = append(used, entry)
+		}
+	}
+	sort.Slice(used, func(a, b int) bool {
+		return used[a].Start < used[b].Start
+	})
+
+	totalDiskSize := float64(header.DiskSize)
+	cursor := header.FirstUsable
+	for _, entry := range used {
+		if entry.Start > cursor {
+			content += fmt.Sprintf("\t\t\t<TD>Libre<br/>%.2f%% del disco</TD>\n", float64(entry.Start-cursor)/totalDiskSize*100)
+		}
+		entryName := strings.TrimRight(string(entry.Name[:]), "\x00")
+		content += fmt.Sprintf("\t\t\t<TD>Primaria<br/>%s<br/>%.2f%% del disco</TD>\n", entryName, float64(entry.Size)/totalDiskSize*100)
+		cursor = entry.Start + entry.Size
+	}
+	if header.LastUsable > cursor {
+		content += fmt.Sprintf("\t\t\t<TD>Libre<br/>%.2f%% del disco</TD>\n", float64(header.LastUsable-cursor)/totalDiskSize*100)
+	}
+
+	content += "\t\t\t</TR>\n"
+	content += "\t\t\t</TABLE>\n>];\n"
+	content += "\t}\n"
+	content += "}\n"
+
+	// Escribir el contenido en el archivo .dot
+	_, err = fileDot.WriteString(content)
+	if err != nil {
+		return fmt.Errorf("Error al escribir en el archivo .dot: %v", err)
+	}
+
+	fmt.Println("Reporte DISK generado exitosamente en:", dotFilePath)
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package Utilities
+
+import (
+	"os"
+	"syscall"
+)
+
+// Función para reservar en el host el espacio de un archivo sin escribirlo (fallocate)
+func Preallocate(file *os.File, size int64) error {
+	return syscall.Fallocate(int(file.Fd()), 0, 0, size)
+}
+
+// Función para obtener los bytes que un archivo ocupa realmente en el host
+// En un archivo disperso (sparse) puede ser mucho menor que su tamaño lógico
+func HostUsage(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Blocks * 512, nil // Blocks siempre se cuenta en unidades de 512 bytes
+	}
+	return info.Size(), nil
+}
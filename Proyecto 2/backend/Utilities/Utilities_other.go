@@ -0,0 +1,24 @@
+//go:build !linux
+
+package Utilities
+
+import (
+	"fmt"
+	"os"
+)
+
+// Función para reservar en el host el espacio de un archivo sin escribirlo
+// Fuera de Linux no hay una forma portable de hacerlo
+func Preallocate(file *os.File, size int64) error {
+	return fmt.Errorf("la reserva de espacio no está disponible en este sistema")
+}
+
+// Función para obtener los bytes que un archivo ocupa realmente en el host
+// Fuera de Linux se usa el tamaño lógico del archivo
+func HostUsage(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -10,6 +11,7 @@ import (
 	"proyecto1/DiskManagement"
 	"proyecto1/FileSystem"
 	"proyecto1/User"
+	"proyecto1/Utilities"
 	"strings"
 )
 
@@ -50,12 +52,31 @@ func ReadMBRHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Handler para obtener el catálogo de discos que conoce el servidor
+func DisksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	disks, err := DiskManagement.ListDisks()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error al leer los discos: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disks)
+}
+
 // Estructura para los parámetros de mkdisk
 type MkDiskParams struct {
-	Size int    `json:"size"`
-	Fit  string `json:"fit"`
-	Unit string `json:"unit"`
-	Path string `json:"path"`
+	Size  int    `json:"size"`
+	Fit   string `json:"fit"`
+	Unit  string `json:"unit"`
+	Path  string `json:"path"`
+	Table string `json:"table"` // mbr (por defecto) o gpt
+	Alloc string `json:"alloc"` // zero (por defecto), sparse o prealloc
 }
 
 // Handler para el comando mkdisk
@@ -86,13 +107,34 @@ func MkDiskHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if _, err := Utilities.ConvertToBytes(params.Size, params.Unit); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		if params.Path == "" {
 			http.Error(w, "La ruta es requerida", http.StatusBadRequest)
 			return
 		}
 
+		if params.Table == "" {
+			params.Table = "mbr"
+		}
+		if params.Table != "mbr" && params.Table != "gpt" {
+			http.Error(w, "La tabla de particiones debe ser 'mbr' o 'gpt'", http.StatusBadRequest)
+			return
+		}
+
+		if params.Alloc == "" {
+			params.Alloc = "zero"
+		}
+		if params.Alloc != "sparse" && params.Alloc != "prealloc" && params.Alloc != "zero" {
+			http.Error(w, "La asignación debe ser 'sparse', 'prealloc' o 'zero'", http.StatusBadRequest)
+			return
+		}
+
 		// Llamar a la función que ejecuta el mkdisk
-		DiskManagement.Mkdisk(params.Size, params.Fit, params.Unit, params.Path)
+		DiskManagement.Mkdisk(params.Size, params.Fit, params.Unit, params.Path, params.Table, params.Alloc)
 
 		// Responder con éxito
 		response := map[string]string{
@@ -104,16 +146,212 @@ func MkDiskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Estructura para los parámetros de rmdisk
+type RmDiskParams struct {
+	Path string `json:"path"`
+}
+
+// Handler para el comando rmdisk (acepta DELETE o POST)
+func RmDiskHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete && r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params RmDiskParams
+
+	// La ruta puede venir en el cuerpo JSON o como parámetro de la URL (?path=...)
+	params.Path = r.URL.Query().Get("path")
+	if params.Path == "" {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Error al procesar la solicitud", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Validaciones
+	if params.Path == "" {
+		http.Error(w, "La ruta es requerida", http.StatusBadRequest)
+		return
+	}
+
+	// Llamar a la función que elimina el disco
+	if err := DiskManagement.Rmdisk(params.Path); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	// Responder con éxito
+	response := map[string]string{
+		"message": "Disco eliminado exitosamente",
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// Estructura para los parámetros de snapshot
+type SnapshotParams struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+}
+
+// Handler para crear (POST) o listar (GET, ?path= opcional) snapshots
+func SnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		snapshots, err := DiskManagement.ListSnapshots(r.URL.Query().Get("path"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if snapshots == nil {
+			snapshots = []DiskManagement.SnapshotInfo{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snapshots)
+	case http.MethodPost:
+		var params SnapshotParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Error al procesar la solicitud", http.StatusBadRequest)
+			return
+		}
+
+		if params.Path == "" || params.Name == "" {
+			http.Error(w, "Para crear un snapshot, se requiere 'path' y 'name'.", http.StatusBadRequest)
+			return
+		}
+
+		snapshot, err := DiskManagement.CreateSnapshot(params.Path, params.Name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snapshot)
+	default:
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+	}
+}
+
+// Estructura para los parámetros de rollback
+type RollbackParams struct {
+	Name  string `json:"name"`
+	Force bool   `json:"force"`
+}
+
+// Handler para el comando rollback
+func RollbackHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params RollbackParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Error al procesar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if params.Name == "" {
+		http.Error(w, "El nombre del snapshot es requerido", http.StatusBadRequest)
+		return
+	}
+
+	if err := DiskManagement.Rollback(params.Name, params.Force); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	response := map[string]string{
+		"message": "Disco restaurado exitosamente",
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// Estructura para los parámetros de migrate
+type MigrateParams struct {
+	Path string `json:"path"`
+}
+
+// Handler para el comando migrate
+func MigrateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params MigrateParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Error al procesar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if params.Path == "" {
+		http.Error(w, "La ruta es requerida", http.StatusBadRequest)
+		return
+	}
+
+	if err := DiskManagement.MigrateDisk(params.Path); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	response := map[string]string{
+		"message": "Disco migrado exitosamente",
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// Estructura para los parámetros de recover
+type RecoverParams struct {
+	Path    string `json:"path"`
+	Confirm bool   `json:"confirm"` // false: solo proponer las particiones encontradas
+}
+
+// Handler para el comando recover
+func RecoverHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params RecoverParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Error al procesar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if params.Path == "" {
+		http.Error(w, "La ruta es requerida", http.StatusBadRequest)
+		return
+	}
+
+	partitions, err := DiskManagement.RecoverPartitions(params.Path, params.Confirm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"path":       params.Path,
+		"confirmed":  params.Confirm,
+		"partitions": partitions,
+	})
+}
+
 // Estructura para los parámetros de fdisk
 type FdiskParams struct {
-	Size   int    `json:"size"`
-	Path   string `json:"path"`
-	Name   string `json:"name"`
-	Unit   string `json:"unit"`
-	Type   string `json:"type"`
-	Fit    string `json:"fit"`
-	Delete string `json:"delete"`
-	Add    int    `json:"add"`
+	Size    int    `json:"size"`
+	Path    string `json:"path"`
+	Name    string `json:"name"`
+	Unit    string `json:"unit"`
+	Type    string `json:"type"`
+	Fit     string `json:"fit"`
+	Delete  string `json:"delete"`
+	Add     int    `json:"add"`
+	Compact bool   `json:"compact"`
+	Check   bool   `json:"check"`
+	Repair  bool   `json:"repair"`
 }
 
 // Handler para el comando fdisk
@@ -127,13 +365,55 @@ func FdiskHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Compactar el disco
+		if params.Compact {
+			if params.Path == "" {
+				http.Error(w, "Para compactar un disco, se requiere 'path'.", http.StatusBadRequest)
+				return
+			}
+
+			if err := DiskManagement.CompactDisk(params.Path); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+
+			response := map[string]string{
+				"message": "Disco compactado exitosamente",
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		// Revisar (y opcionalmente reparar) la tabla de particiones
+		if params.Check || params.Repair {
+			if params.Path == "" {
+				http.Error(w, "Para revisar un disco, se requiere 'path'.", http.StatusBadRequest)
+				return
+			}
+
+			issues, err := DiskManagement.CheckDisk(params.Path, params.Repair)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if issues == nil {
+				issues = []DiskManagement.DiskIssue{}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"path":   params.Path,
+				"issues": issues,
+			})
+			return
+		}
+
 		if params.Delete != "" {
 			if params.Path == "" || params.Name == "" {
 				http.Error(w, "Para eliminar una partición, se requiere 'path' y 'name'.", http.StatusBadRequest)
 				return
 			}
-			lowercaseName := strings.ToLower(params.Name)
-			DiskManagement.DeletePartition(params.Path, lowercaseName, params.Delete)
+			DiskManagement.DeletePartition(params.Path, params.Name, params.Delete)
 
 			response := map[string]string{
 				"message": "Partición eliminada exitosamente",
@@ -149,8 +429,7 @@ func FdiskHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			lowercaseName := strings.ToLower(params.Name)
-			err := DiskManagement.ModifyPartition(params.Path, lowercaseName, params.Add, params.Unit)
+			err := DiskManagement.ModifyPartition(params.Path, params.Name, params.Add, params.Unit)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest) // Manejo de error
 				return
@@ -184,17 +463,17 @@ func FdiskHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if params.Fit != "b" && params.Fit != "f" && params.Fit != "w" {
+		// Si no se envía el ajuste, Fdisk usa el ajuste del disco
+		if params.Fit != "" && params.Fit != "b" && params.Fit != "f" && params.Fit != "w" {
 			http.Error(w, "El ajuste debe ser 'b', 'f', o 'w'", http.StatusBadRequest)
 			return
 		}
 
-		if params.Fit == "" {
-			params.Fit = "w"
-		}
-
 		lowercaseName := strings.ToLower(params.Name)
-		DiskManagement.Fdisk(params.Size, params.Path, lowercaseName, params.Unit, params.Type, params.Fit)
+		if err := DiskManagement.Fdisk(params.Size, params.Path, lowercaseName, params.Unit, params.Type, params.Fit); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		response := map[string]string{
 			"message": "Partición creada exitosamente",
@@ -205,6 +484,121 @@ func FdiskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Estructura para los parámetros de cpart
+type CpartParams struct {
+	Src     string `json:"src"`
+	SrcName string `json:"srcname"`
+	Dest    string `json:"dest"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+}
+
+// Handler para el comando cpart (copiar una partición entre discos)
+func CpartHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params CpartParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Error al procesar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if params.Src == "" || params.SrcName == "" || params.Dest == "" || params.Name == "" {
+		http.Error(w, "Se requieren 'src', 'srcname', 'dest' y 'name'", http.StatusBadRequest)
+		return
+	}
+
+	// Por defecto la copia es una partición primaria
+	if params.Type == "" {
+		params.Type = "p"
+	}
+
+	err := DiskManagement.CopyPartition(params.Src, params.SrcName, params.Dest, strings.ToLower(params.Name), strings.ToLower(params.Type))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]string{
+		"message": "Partición copiada exitosamente",
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// Handler para descargar la imagen de una partición (dumppart). La partición se indica en la URL con
+// ?id=<ID> o con ?path=<disco>&name=<nombre> y la respuesta son los bytes de la partición.
+func DumpPartHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	id, path, name := query.Get("id"), query.Get("path"), query.Get("name")
+	if id == "" && (path == "" || name == "") {
+		http.Error(w, "Se requiere 'id' o 'path' y 'name'", http.StatusBadRequest)
+		return
+	}
+
+	fileName := id
+	if fileName == "" {
+		fileName = name
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName+".img"))
+
+	// Si la partición no se encuentra todavía no se ha escrito nada y se puede responder con el error
+	if _, err := DiskManagement.DumpPartitionTo(path, name, id, w); err != nil {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+// Handler para subir una imagen a una partición existente (loadpart). La partición se indica en la URL con
+// ?path=<disco>&name=<nombre> y el cuerpo de la solicitud son los bytes de la imagen.
+func LoadPartHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path, name := r.URL.Query().Get("path"), r.URL.Query().Get("name")
+	if path == "" || name == "" {
+		http.Error(w, "Se requiere 'path' y 'name'", http.StatusBadRequest)
+		return
+	}
+
+	// Guardar la imagen completa antes de tocar el disco, para validar su tamaño con la partición
+	temp, err := os.CreateTemp("", "loadpart-*.img")
+	if err != nil {
+		http.Error(w, "Error al guardar la imagen: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(temp.Name())
+	_, err = io.Copy(temp, r.Body)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		http.Error(w, "Error al recibir la imagen: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := DiskManagement.LoadPartition(path, name, temp.Name()); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	response := map[string]string{
+		"message": "Imagen importada exitosamente",
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
 // Estructura para los parámetros de mount
 type MountParams struct {
 	Path string `json:"path"`
@@ -233,14 +627,8 @@ func MountHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convertir el nombre a minúsculas y eliminar cualquier comilla adicional
-	lowercaseName := strings.ToLower(strings.Trim(params.Name, "\""))
-
-	// Imprimir el nombre procesado (opcional, para depurar)
-	//fmt.Println("Montando partición con nombre en minúsculas (sin comillas):", lowercaseName)
-
-	// Llamar a la función que ejecuta el mount (sin capturar un valor de retorno)
-	DiskManagement.Mount(params.Path, lowercaseName)
+	// Llamar a la función que ejecuta el mount (el nombre se busca sin distinguir mayúsculas)
+	DiskManagement.Mount(params.Path, params.Name)
 
 	// Responder con éxito
 	response := map[string]string{
@@ -343,6 +731,151 @@ func MkfsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Estructura para los parámetros de resizefs
+type ResizefsParams struct {
+	ID string `json:"id"`
+}
+
+// Handler para el comando resizefs
+func ResizefsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params ResizefsParams
+
+	// Decodificar el cuerpo JSON de la solicitud
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		http.Error(w, "Error al procesar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if params.ID == "" {
+		http.Error(w, "El ID es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	// Llamar a la función que redimensiona el sistema de archivos
+	if err := FileSystem.Resizefs(params.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	response := map[string]string{
+		"message": "Sistema de archivos redimensionado exitosamente",
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// Estructura para los parámetros de mkdir
+type MkdirParams struct {
+	Path string `json:"path"`
+	P    bool   `json:"p"` // true: crear también las carpetas padre que no existan
+}
+
+// Handler para el comando mkdir
+func MkdirHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params MkdirParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Error al procesar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if params.Path == "" {
+		http.Error(w, "La ruta es requerida", http.StatusBadRequest)
+		return
+	}
+
+	if err := FileSystem.Mkdir(params.Path, params.P); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	response := map[string]string{
+		"message": "Carpeta creada exitosamente",
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// Estructura para los parámetros de mkfile
+type MkfileParams struct {
+	Path string `json:"path"`
+	Size int    `json:"size"`
+	R    bool   `json:"r"`    // true: crear también las carpetas padre que no existan
+	Cont string `json:"cont"` // Archivo del servidor con el contenido (tiene prioridad sobre size)
+}
+
+// Handler para el comando mkfile
+func MkfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params MkfileParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Error al procesar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if params.Path == "" {
+		http.Error(w, "La ruta es requerida", http.StatusBadRequest)
+		return
+	}
+
+	if err := FileSystem.Mkfile(params.Path, params.Size, params.R, params.Cont); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	response := map[string]string{
+		"message": "Archivo creado exitosamente",
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// Estructura para los parámetros de cat
+type CatParams struct {
+	Files []string `json:"files"` // Rutas de los archivos en el orden en que se concatenan
+}
+
+// Handler para el comando cat
+func CatHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var params CatParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "Error al procesar la solicitud", http.StatusBadRequest)
+		return
+	}
+
+	if len(params.Files) == 0 {
+		http.Error(w, "Se necesita al menos un archivo", http.StatusBadRequest)
+		return
+	}
+
+	content, err := FileSystem.Cat(params.Files)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	response := map[string]string{
+		"content": content,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
 // Estructura para los parámetros de login
 type LoginParams struct {
 	User string `json:"user"`
@@ -422,17 +955,8 @@ func RepHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Verificar si la partición está montada
-		mounted := false
-		var diskPath string
-		for _, partitions := range DiskManagement.GetMountedPartitions() {
-			for _, partition := range partitions {
-				if partition.ID == params.ID {
-					mounted = true
-					diskPath = partition.Path
-					break
-				}
-			}
-		}
+		mountedPartition, mounted := DiskManagement.GetMountedPartition(params.ID)
+		diskPath := mountedPartition.Path
 
 		if !mounted {
 			http.Error(w, "La partición con ID "+params.ID+" no está montada", http.StatusBadRequest)
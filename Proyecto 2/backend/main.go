@@ -34,10 +34,13 @@ func init() {
 	}
 }
 
-// Función para limpiar las particiones montadas
-func CleanMountedPartitions() {
-	DiskManagement.CleanMountedPartitions()
-	fmt.Println("Particiones montadas limpiadas por finalización del programa.")
+// Función para guardar las particiones montadas antes de finalizar, para restaurarlas al reiniciar
+func SaveMountedPartitions() {
+	if err := DiskManagement.SaveMountRegistry(); err != nil {
+		fmt.Println("Error al guardar las particiones montadas:", err)
+		return
+	}
+	fmt.Println("Particiones montadas guardadas por finalización del programa.")
 }
 
 func main() {
@@ -54,6 +57,23 @@ func main() {
 		fmt.Println("Ejecución en modo de desarrollo")
 	}
 
+	// Configurar el prefijo de los IDs de montaje
+	if err := DiskManagement.SetMountIDPrefix(os.Getenv("MOUNT_ID_PREFIX")); err != nil {
+		log.Fatalf("Error en MOUNT_ID_PREFIX: %v", err)
+	}
+
+	// Restaurar las particiones montadas en la ejecución anterior
+	DiskManagement.SetMountRegistryPath(os.Getenv("MOUNT_REGISTRY"))
+	if err := DiskManagement.LoadMountRegistry(); err != nil {
+		fmt.Println("Error al restaurar las particiones montadas:", err)
+	}
+
+	// Configurar la carpeta de los snapshots de discos
+	DiskManagement.SetSnapshotsDir(os.Getenv("SNAPSHOTS_DIR"))
+
+	// Configurar la carpeta donde se buscan los discos para el catálogo
+	DiskManagement.SetDisksDir(os.Getenv("DISKS_DIR"))
+
 	// Capturar señales del sistema para limpiar antes de finalizar
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
@@ -61,20 +81,33 @@ func main() {
 	go func() {
 		sig := <-signals
 		fmt.Println("Recibida señal:", sig)
-		CleanMountedPartitions()
+		SaveMountedPartitions()
 		os.Exit(0)
 	}()
 
 	// Crear un multiplexor para manejar las rutas
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/mkdisk", MkDiskHandler)
+	mux.HandleFunc("/api/rmdisk", RmDiskHandler)
 	mux.HandleFunc("/api/fdisk", FdiskHandler)
+	mux.HandleFunc("/api/cpart", CpartHandler)
+	mux.HandleFunc("/api/dumppart", DumpPartHandler)
+	mux.HandleFunc("/api/loadpart", LoadPartHandler)
+	mux.HandleFunc("/api/snapshot", SnapshotHandler)
+	mux.HandleFunc("/api/rollback", RollbackHandler)
+	mux.HandleFunc("/api/migrate", MigrateHandler)
+	mux.HandleFunc("/api/recover", RecoverHandler)
 	mux.HandleFunc("/api/mount", MountHandler)
 	mux.HandleFunc("/api/unmount", UnmountHandler)
 	mux.HandleFunc("/api/mkfs", MkfsHandler)
+	mux.HandleFunc("/api/resizefs", ResizefsHandler)
 	mux.HandleFunc("/api/login", LoginHandler)
+	mux.HandleFunc("/api/mkdir", MkdirHandler)
+	mux.HandleFunc("/api/mkfile", MkfileHandler)
+	mux.HandleFunc("/api/cat", CatHandler)
 	mux.HandleFunc("/api/rep", RepHandler)
 	mux.HandleFunc("/api/readmbr", ReadMBRHandler)
+	mux.HandleFunc("/api/disks", DisksHandler)
 
 	// Iniciar el servidor con el middleware de CORS habilitado
 	fmt.Printf("Servidor ejecutándose en el puerto %s\n", port)
@@ -6,9 +6,16 @@ const Visualizador = () => {
   const [selectedDisk, setSelectedDisk] = useState("");
 
   useEffect(() => {
-    // Leer los discos desde localStorage
-    const storedDisks = JSON.parse(localStorage.getItem("disks")) || [];
-    setDisks(storedDisks);
+    // Leer el catálogo de discos desde el servidor
+    fetch("http://localhost:8080/api/disks")
+      .then((response) => response.json())
+      .then((data) => {
+        setDisks(data || []);
+      })
+      .catch((error) => {
+        console.error("Error al obtener los discos:", error);
+        setDisks([]);
+      });
   }, []);
 
   // Función para obtener solo el nombre del archivo del path
@@ -16,27 +23,10 @@ const Visualizador = () => {
     return path.split('/').pop();
   };
 
-  // Función para obtener las particiones de un disco
-  const fetchPartitions = (diskPath) => {
-    // Guardar el disco seleccionado
-    setSelectedDisk(getDiskName(diskPath));
-
-    // Hacer la solicitud al backend para obtener las particiones
-    fetch("http://localhost:8080/api/readmbr", {
-      method: "POST",
-      headers: {
-        "Content-Type": "application/json",
-      },
-      body: JSON.stringify({ path: diskPath }), 
-    })
-      .then((response) => response.json())
-      .then((data) => {
-        setPartitions(data || []); 
-      })
-      .catch((error) => {
-        console.error("Error al obtener particiones:", error);
-        setPartitions([]); 
-      });
+  // Función para mostrar las particiones de un disco (el catálogo ya incluye las lógicas)
+  const showPartitions = (disk) => {
+    setSelectedDisk(getDiskName(disk.path));
+    setPartitions(disk.partitions || []);
   };
 
   return (
@@ -50,10 +40,18 @@ const Visualizador = () => {
                 className="card mb-3"
                 style={{ cursor: "pointer" }}
                 // Al hacer clic en el disco, obtener particiones
-                onClick={() => fetchPartitions(disk)} 
+                onClick={() => showPartitions(disk)}
               >
                 <div className="card-body">
-                  <h5 className="card-title">Disco: {getDiskName(disk)}</h5>
+                  <h5 className="card-title">Disco: {getDiskName(disk.path)}</h5>
+                  <p className="card-text mb-0">
+                    {disk.size} bytes | {disk.table ? disk.table.toUpperCase() : "?"} | Fit: {disk.fit}
+                  </p>
+                  <p className="card-text mb-0">Creado: {disk.creation_date}</p>
+                  {disk.mount_ids && disk.mount_ids.length > 0 && (
+                    <p className="card-text mb-0">Montadas: {disk.mount_ids.join(", ")}</p>
+                  )}
+                  {disk.error && <p className="card-text text-danger mb-0">{disk.error}</p>}
                 </div>
               </div>
             </div>
@@ -72,6 +70,11 @@ const Visualizador = () => {
                 <li key={index} className="list-group-item">
                   <strong>Nombre:</strong> {partition.name} | <strong>Tipo:</strong> {partition.type} |{" "}
                   <strong>Tamaño:</strong> {partition.size} | <strong>Inicio:</strong> {partition.start}
+                  {partition.id && (
+                    <>
+                      {" "}| <strong>ID:</strong> {partition.id}
+                    </>
+                  )}
                 </li>
               ))}
             </ul>
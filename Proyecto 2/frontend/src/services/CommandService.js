@@ -3,16 +3,8 @@ const CommandService = {
   parseAndSend: async (command) => {
     const parsedCommand = CommandService.parseCommand(command);
     if (parsedCommand) {
-      const result = await CommandService.sendCommand(parsedCommand);
-      
-      // Detectar si el comando es mkdisk y almacenar el path del disco en localStorage
-      if (command.startsWith("mkdisk")) {
-        const disks = JSON.parse(localStorage.getItem("disks")) || [];
-        disks.push(parsedCommand.body.path);  // Almacenar solo el path del disco creado
-        localStorage.setItem("disks", JSON.stringify(disks));
-      }
-
-      return result;
+      // La lista de discos la mantiene el servidor (GET /api/disks), no hace falta guardarla aquí
+      return await CommandService.sendCommand(parsedCommand);
     } else {
       throw new Error("Comando no válido o no soportado");
     }
@@ -30,11 +22,40 @@ const CommandService = {
           size: parseInt(params.size, 10),
           fit: params.fit.toLowerCase(),
           unit: params.unit.toLowerCase(),
+          path: params.path,
+          table: params.table ? params.table.toLowerCase() : "mbr",
+          alloc: params.alloc ? params.alloc.toLowerCase() : "zero"
+        }
+      };
+    } else if (command.startsWith("rmdisk")) {
+      return {
+        url: "http://localhost:8080/api/rmdisk",
+        method: "DELETE",
+        body: {
           path: params.path
         }
       };
     } else if (command.startsWith("fdisk")) {
-      if (params.delete) {
+      if (/(^|\s)-compact(\s|$)/i.test(command)) {
+        return {
+          url: "http://localhost:8080/api/fdisk",
+          method: "POST",
+          body: {
+            compact: true,
+            path: params.path
+          }
+        };
+      } else if (/(^|\s)-(check|repair)(\s|$)/i.test(command)) {
+        return {
+          url: "http://localhost:8080/api/fdisk",
+          method: "POST",
+          body: {
+            check: true,
+            repair: /(^|\s)-repair(\s|$)/i.test(command),
+            path: params.path
+          }
+        };
+      } else if (params.delete) {
         return {
           url: "http://localhost:8080/api/fdisk",
           method: "POST",
@@ -65,10 +86,72 @@ const CommandService = {
             name: params.name.toLowerCase(),
             unit: params.unit.toLowerCase(),
             type: params.type.toLowerCase(),
-            fit: params.fit ? params.fit.toLowerCase() : "w"
+            fit: params.fit ? params.fit.toLowerCase() : ""
           }
         };
       }
+    } else if (command.startsWith("cpart")) {
+      return {
+        url: "http://localhost:8080/api/cpart",
+        method: "POST",
+        body: {
+          src: params.src,
+          srcname: params.srcname.toLowerCase(),
+          dest: params.dest,
+          name: params.name.toLowerCase(),
+          type: params.type ? params.type.toLowerCase() : "p"
+        }
+      };
+    } else if (command.startsWith("snapshot")) {
+      if (/(^|\s)-list(\s|$)/i.test(command)) {
+        const query = params.path ? `?path=${encodeURIComponent(params.path)}` : "";
+        return {
+          url: `http://localhost:8080/api/snapshot${query}`,
+          method: "GET"
+        };
+      }
+      return {
+        url: "http://localhost:8080/api/snapshot",
+        method: "POST",
+        body: {
+          path: params.path,
+          name: params.name.toLowerCase()
+        }
+      };
+    } else if (command.startsWith("rollback")) {
+      return {
+        url: "http://localhost:8080/api/rollback",
+        method: "POST",
+        body: {
+          name: params.name.toLowerCase(),
+          force: /(^|\s)-force(\s|$)/i.test(command)
+        }
+      };
+    } else if (command.startsWith("migrate")) {
+      return {
+        url: "http://localhost:8080/api/migrate",
+        method: "POST",
+        body: {
+          path: params.path
+        }
+      };
+    } else if (command.startsWith("dumppart")) {
+      // La imagen de la partición se descarga en el navegador con el nombre indicado en -out
+      const query = new URLSearchParams(params.id ? { id: params.id } : { path: params.path, name: params.name });
+      return {
+        url: `http://localhost:8080/api/dumppart?${query}`,
+        method: "GET",
+        download: params.out ? params.out.split("/").pop() : `${params.id || params.name}.img`
+      };
+    } else if (command.startsWith("recover")) {
+      return {
+        url: "http://localhost:8080/api/recover",
+        method: "POST",
+        body: {
+          path: params.path,
+          confirm: /(^|\s)-confirm(\s|$)/i.test(command)
+        }
+      };
     } else if (command.startsWith("mount")) {
       return {
         url: "http://localhost:8080/api/mount",
@@ -88,6 +171,14 @@ const CommandService = {
           fs: params.fs ? params.fs.toLowerCase() : "2fs"
         }
       };
+    } else if (command.startsWith("resizefs")) {
+      return {
+        url: "http://localhost:8080/api/resizefs",
+        method: "POST",
+        body: {
+          id: params.id
+        }
+      };
     } else if (command.startsWith("login")) {
       return {
         url: "http://localhost:8080/api/login",
@@ -98,6 +189,39 @@ const CommandService = {
           id: params.id
         }
       };
+    } else if (command.startsWith("mkdir")) {
+      return {
+        url: "http://localhost:8080/api/mkdir",
+        method: "POST",
+        body: {
+          path: params.path,
+          p: /(^|\s)-p(\s|$)/i.test(command)
+        }
+      };
+    } else if (command.startsWith("mkfile")) {
+      return {
+        url: "http://localhost:8080/api/mkfile",
+        method: "POST",
+        body: {
+          path: params.path,
+          size: params.size ? parseInt(params.size, 10) : 0,
+          r: /(^|\s)-r(\s|$)/i.test(command),
+          cont: params.cont || ""
+        }
+      };
+    } else if (command.startsWith("cat")) {
+      // -file1, -file2, ... -fileN en el orden de su número
+      const files = Object.keys(params)
+        .filter((key) => /^file\d+$/i.test(key))
+        .sort((a, b) => parseInt(a.slice(4), 10) - parseInt(b.slice(4), 10))
+        .map((key) => params[key]);
+      return {
+        url: "http://localhost:8080/api/cat",
+        method: "POST",
+        body: {
+          files: files
+        }
+      };
     } else if (command.startsWith("readmbr")) {
       return {
         url: "http://localhost:8080/api/readmbr",
@@ -143,7 +267,8 @@ const CommandService = {
         headers: {
           "Content-Type": "application/json"
         },
-        body: JSON.stringify(parsedCommand.body)
+        // Las solicitudes GET no llevan cuerpo
+        body: parsedCommand.body ? JSON.stringify(parsedCommand.body) : undefined
       });
 
       // Si el estado no es ok, arroja un error con el contenido de la respuesta
@@ -152,6 +277,17 @@ const CommandService = {
         throw new Error(errorData.error || `Error en el servidor: ${response.statusText}`);
       }
 
+      // Las descargas (dumppart) se guardan como archivo en lugar de mostrarse
+      if (parsedCommand.download) {
+        const blob = await response.blob();
+        const link = document.createElement("a");
+        link.href = URL.createObjectURL(blob);
+        link.download = parsedCommand.download;
+        link.click();
+        URL.revokeObjectURL(link.href);
+        return { message: `Imagen descargada como ${parsedCommand.download} (${blob.size} bytes)` };
+      }
+
       // Devolver la respuesta en JSON si todo está bien
       return await response.json();
     } catch (error) {